@@ -10,6 +10,7 @@ import (
 	"time"
 
 	h "github.com/assaidy/iWonder/internals/handlers"
+	"github.com/assaidy/iWonder/internals/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	_ "github.com/joho/godotenv/autoload"
@@ -34,7 +35,63 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	return c.Status(code).SendString(err.Error())
 }
 
+// runCleanups periodically deletes what outlived its use: expired and interrupted data exports, abandoned oidc
+// login states and codes, expired action tokens and two factor challenges, and the accounts whose deletion
+// grace period is over.
+func runCleanups() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for ; true; <-ticker.C {
+		if err := h.DeleteExpiredDataExports(); err != nil {
+			slog.Error("error deleting expired data exports", "err", err)
+		}
+		if err := h.DeleteExpiredOidcLoginStates(); err != nil {
+			slog.Error("error deleting expired oidc login states", "err", err)
+		}
+		if err := h.DeleteExpiredActionTokens(); err != nil {
+			slog.Error("error deleting expired action tokens", "err", err)
+		}
+		if err := h.DeleteExpiredTwoFactorChallenges(); err != nil {
+			slog.Error("error deleting expired two factor challenges", "err", err)
+		}
+		for {
+			deletedCount, err := h.DeleteDueUsers(100)
+			if err != nil {
+				slog.Error("error deleting due users", "err", err)
+				break
+			}
+			if deletedCount > 0 {
+				slog.Info("deleted due users", "count", deletedCount)
+			}
+			if deletedCount < 100 {
+				break
+			}
+		}
+	}
+}
+
+// evaluateBadges periodically awards the badges users earned since the last run.
+func evaluateBadges() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+	for ; true; <-ticker.C {
+		awardedCount, err := h.EvaluateBadges()
+		if err != nil {
+			slog.Error("error evaluating badges", "err", err)
+		}
+		if awardedCount > 0 {
+			slog.Info("awarded badges", "count", awardedCount)
+		}
+	}
+}
+
 func main() {
+	if promotedCount, err := h.PromoteBootstrapAdmins(); err != nil {
+		log.Fatal("error promoting bootstrap admins: ", err)
+	} else if promotedCount > 0 {
+		slog.Info("promoted bootstrap admins", "count", promotedCount)
+	}
+
 	app := fiber.New(fiber.Config{
 		AppName:      "iWonder",
 		ServerHeader: "iWonder",
@@ -42,42 +99,105 @@ func main() {
 		ErrorHandler: errorHandler,
 	})
 
+	app.Get("/.well-known/jwks.json", h.HandleGetJWKS)
+	if localStorage, ok := storage.Default.(*storage.LocalStorage); ok {
+		app.Static("/media", localStorage.Dir, fiber.Static{MaxAge: 30 * 24 * 60 * 60})
+	}
+
 	v1 := app.Group("/v1", logger.New(logger.Config{
 		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error} | ${respHeader:Content-Type} | ${resBody}\n",
 	}))
 	{
 		v1.Post("/users/register", h.HandleRegister)
 		v1.Post("/users/login", h.HandleLogin)
+		v1.Post("/users/login/2fa", h.HandleLoginTwoFactor)
+		v1.Get("/users/oidc/:provider/login", h.HandleOidcLogin)
+		v1.Get("/users/oidc/:provider/callback", h.HandleOidcCallback)
+		v1.Post("/users/oidc/token", h.HandleOidcToken)
 		v1.Post("/users/access_token", h.HandleGetAccessToken)
+		v1.Post("/users/logout", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleLogout)
+		v1.Get("/users/sessions", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleGetSessions)
+		v1.Delete("/users/sessions", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDeleteAllSessions)
+		v1.Delete("/users/sessions/:session_id", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDeleteSession)
 		v1.Get("/users/id/:user_id", h.HandleGetUserByID)
 		v1.Get("/users/username/:username", h.HandleGetUserByUsername)
-		v1.Put("/users", h.WithJwt, h.HandleUpdateUser)
-		v1.Delete("/users", h.WithJwt, h.HandleDeleteUser)
+		v1.Patch("/users", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUpdateUser)
+		v1.Put("/users/password", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUpdatePassword)
+		v1.Post("/users/password/forgot", h.HandleForgotPassword)
+		v1.Post("/users/password/reset", h.HandleResetPassword)
+		v1.Put("/users/email", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUpdateEmail)
+		v1.Post("/users/email/verification", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleSendEmailVerification)
+		v1.Post("/users/email/verify", h.HandleVerifyEmail) // ?token=xyz
+		v1.Post("/users/2fa/enroll", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleEnrollTwoFactor)
+		v1.Post("/users/2fa/confirm", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleConfirmTwoFactor)
+		v1.Delete("/users/2fa", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDisableTwoFactor)
+		v1.Post("/users/tokens", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleCreatePersonalAccessToken)
+		v1.Get("/users/tokens", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleGetPersonalAccessTokens)
+		v1.Delete("/users/tokens/:token_id", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDeletePersonalAccessToken)
+		v1.Delete("/users", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDeleteUser)
+		v1.Put("/users/avatar", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUpdateAvatar)
+		v1.Delete("/users/avatar", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleDeleteAvatar)
+		v1.Get("/users/id/:user_id/identicon", h.HandleGetIdenticon) // ?size=64
+		v1.Delete("/users/deletion", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleCancelUserDeletion)
+		v1.Post("/users/export", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleCreateDataExport)
+		v1.Get("/users/exports/:export_id", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleGetDataExport)
+		v1.Get("/users/exports/:export_id/download", h.HandleDownloadDataExport) // ?token=xyz
+
+		v1.Post("/posts", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleCreatePost)
+		v1.Get("/posts/:post_id", h.WithOptionalJwt, h.HandleGetPost)
+		v1.Put("/posts/:post_id", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleUpdatePost)
+		v1.Delete("/posts/:post_id", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleDeletePost)
 
-		v1.Post("/posts", h.WithJwt, h.HandleCreatePost)
-		v1.Get("/posts/:post_id", h.HandleGetPost)
-		v1.Put("/posts/:post_id", h.WithJwt, h.HandleUpdatePost)
-		v1.Delete("/posts/:post_id", h.WithJwt, h.HandleDeletePost)
+		v1.Get("/posts/:post_id/revisions", h.HandleGetPostRevisions)
+		v1.Get("/posts/:post_id/revisions/diff", h.HandleGetPostRevisionsDiff) // ?from=1&to=2&mode=line|word
+		v1.Get("/posts/:post_id/revisions/:revision", h.HandleGetPostRevision)
+		v1.Post("/posts/:post_id/revisions/:revision/rollback", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleRollbackPost)
 
-		v1.Post("/posts/:post_id/tags", h.WithJwt, h.HandleAddPostTags)
+		v1.Post("/posts/:post_id/tags", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleAddPostTags)
 		v1.Get("/posts/:post_id/tags", h.HandleGetPostTags)
-		v1.Delete("/posts/:post_id/tags/:tag_name", h.WithJwt, h.HandleDeletePostTag)
+		v1.Delete("/posts/:post_id/tags/:tag_name", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleDeletePostTag)
 
-		v1.Post("/posts/:post_id/comments", h.WithJwt, h.HandleCreateComment)
-		v1.Put("/posts/comments/:comment_id", h.WithJwt, h.HandleUpdateComment)
-		v1.Delete("/posts/comments/:comment_id", h.WithJwt, h.HandleDeleteComment)
-		v1.Get("/posts/:post_id/comments", h.HandleGetAllPostComments)
+		v1.Post("/posts/:post_id/comments", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleCreateComment)
+		v1.Put("/posts/comments/:comment_id", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleUpdateComment)
+		v1.Delete("/posts/comments/:comment_id", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleDeleteComment)
+		v1.Get("/posts/comments/:comment_id/revisions", h.HandleGetCommentRevisions)
+		v1.Get("/posts/:post_id/comments", h.WithOptionalJwt, h.HandleGetAllPostComments) // ?sort=newest|score
 
-		v1.Post("/posts/comments/:comment_id/votes", h.WithJwt, h.HandleVoteComment)
-		v1.Delete("/posts/comments/:comment_id/votes", h.WithJwt, h.HandleUnvoteComment)
+		v1.Post("/posts/comments/:comment_id/votes", h.WithJwt, h.RequireScope(h.ScopeVotesWrite), h.HandleVoteComment)
+		v1.Delete("/posts/comments/:comment_id/votes", h.WithJwt, h.RequireScope(h.ScopeVotesWrite), h.HandleUnvoteComment)
 		v1.Get("/posts/comments/:comment_id/votes", h.HandleGetCommentVoteCounts)
 
-		v1.Post("/posts/:post_id/answer", h.WithJwt, h.HandleSetPostAnswer)
-		v1.Delete("/posts/:post_id/answer", h.WithJwt, h.HandleUnsetPostAnswer)
+		v1.Post("/posts/:post_id/votes", h.WithJwt, h.RequireScope(h.ScopeVotesWrite), h.HandleVotePost) // ?kind=up|down
+		v1.Delete("/posts/:post_id/votes", h.WithJwt, h.RequireScope(h.ScopeVotesWrite), h.HandleUnvotePost)
+		v1.Get("/posts/:post_id/votes", h.WithOptionalJwt, h.HandleGetPostVoteCounts)
+
+		v1.Post("/posts/:post_id/answer", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleSetPostAnswer)
+		v1.Delete("/posts/:post_id/answer", h.WithJwt, h.RequireScope(h.ScopePostsWrite), h.HandleUnsetPostAnswer)
 		v1.Get("/posts/:post_id/answer", h.HandleGetPostAnswer)
 
-		v1.Get("users/:user_id/posts", h.HandleGetAllPostsForUser)
-		v1.Get("/posts", h.HandleGetAllPosts) // ?query=xyz&tags=x,y,z
+		v1.Get("users/:user_id/posts", h.WithOptionalJwt, h.HandleGetAllPostsForUser)
+		v1.Get("/users/:user_id/reputation", h.HandleGetUserReputation)
+		v1.Get("/users/:user_id/privileges", h.HandleGetUserPrivileges)
+		v1.Get("/users/:user_id/badges", h.HandleGetUserBadges)
+		v1.Post("/users/:user_id/follow", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleFollowUser)
+		v1.Delete("/users/:user_id/follow", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUnfollowUser)
+		v1.Get("/users/:user_id/followers", h.HandleGetUserFollowers)
+		v1.Get("/users/:user_id/following", h.HandleGetUserFollowing)
+		v1.Get("/users/blocks", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleGetUserBlocks) // ?kind=block|mute
+		v1.Post("/users/:user_id/block", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleBlockUser)
+		v1.Delete("/users/:user_id/block", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUnblockUser)
+		v1.Post("/users/:user_id/mute", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleMuteUser)
+		v1.Delete("/users/:user_id/mute", h.WithJwt, h.RequireScope(h.ScopeAccount), h.HandleUnmuteUser)
+
+		v1.Get("/feed/following", h.WithJwt, h.RequireScope(h.ScopePostsRead), h.HandleGetFollowingFeed)
+
+		v1.Get("/badges", h.HandleGetBadges)
+		v1.Get("/badges/awards", h.HandleGetRecentBadgeAwards)
+		v1.Get("/posts", h.WithOptionalJwt, h.HandleGetAllPosts) // ?query=xyz&tags=x,y,z
+
+		v1.Delete("/admin/users/:user_id/login_lock", h.WithJwt, h.RequireScope(h.ScopeAccount), h.RequireRole(h.RoleAdmin), h.HandleUnlockUserLogin)
+		v1.Put("/admin/users/:user_id/role", h.WithJwt, h.RequireScope(h.ScopeAccount), h.RequireRole(h.RoleAdmin), h.HandleSetUserRole)
+		v1.Get("/admin/moderation_actions", h.WithJwt, h.RequireScope(h.ScopeAccount), h.RequireRole(h.RoleModerator), h.HandleGetModerationActions)
 	}
 
 	go func() {
@@ -86,6 +206,9 @@ func main() {
 		}
 	}()
 
+	go runCleanups()
+	go evaluateBadges()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
 	<-sigChan
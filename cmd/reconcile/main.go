@@ -0,0 +1,26 @@
+// reconcile recomputes the vote counts of posts and comments from their votes, in case they drifted.
+// It's safe to run while the api is up.
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+
+	h "github.com/assaidy/iWonder/internals/handlers"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "number of rows reconciled in every transaction")
+	flag.Parse()
+	if *batchSize < 1 {
+		log.Fatal("batch must be positive")
+	}
+
+	fixedPostsCount, fixedCommentsCount, err := h.ReconcileVoteCounts(int32(*batchSize))
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("reconciled vote counts", "fixedPosts", fixedPostsCount, "fixedComments", fixedCommentsCount)
+}
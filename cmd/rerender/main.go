@@ -0,0 +1,41 @@
+// rerender re-renders the cached html of every post and comment that was rendered by another version of
+// the markdown renderer. Run it after bumping utils.MarkdownRendererVersion, it's safe to run while the api is up.
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+
+	h "github.com/assaidy/iWonder/internals/handlers"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "number of rows re-rendered in every transaction")
+	flag.Parse()
+	if *batchSize < 1 {
+		log.Fatal("batch must be positive")
+	}
+
+	for _, target := range []struct {
+		name     string
+		rerender func(limit int32) (int, error)
+	}{
+		{"posts", h.RerenderStalePosts},
+		{"comments", h.RerenderStaleComments},
+	} {
+		total := 0
+		for {
+			count, err := target.rerender(int32(*batchSize))
+			if err != nil {
+				log.Fatalf("error re-rendering %s: %v", target.name, err)
+			}
+			total += count
+			if count < *batchSize {
+				break
+			}
+		}
+		slog.Info("re-rendered "+target.name, "count", total)
+	}
+}
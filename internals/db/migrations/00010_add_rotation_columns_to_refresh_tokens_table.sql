@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table refresh_tokens
+    add column family_id uuid not null default gen_random_uuid(),
+    add column parent_token varchar(100),
+    add column used_at timestamptz;
+
+alter table refresh_tokens alter column family_id drop default;
+
+create index on refresh_tokens(family_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table refresh_tokens
+    drop column family_id,
+    drop column parent_token,
+    drop column used_at;
+-- +goose StatementEnd
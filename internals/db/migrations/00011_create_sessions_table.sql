@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+create table sessions (
+    id uuid default gen_random_uuid(),
+    user_id uuid not null,
+    user_agent varchar not null default '',
+    ip_address varchar(45) not null default '',
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create index on sessions(user_id);
+
+insert into sessions (id, user_id, created_at)
+select family_id, user_id, min(created_at)
+from refresh_tokens
+group by family_id, user_id;
+
+alter table refresh_tokens
+    add foreign key (family_id) references sessions (id) on delete cascade;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table refresh_tokens drop constraint refresh_tokens_family_id_fkey;
+drop table sessions;
+-- +goose StatementEnd
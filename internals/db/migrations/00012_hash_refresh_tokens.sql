@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: refresh tokens were stored raw, the stored value becomes the hex encoded sha-256 digest of the token,
+-- so existing tokens keep working without ever being persisted in plain text again.
+alter table refresh_tokens rename column token to token_hash;
+alter table refresh_tokens rename column parent_token to parent_token_hash;
+alter table refresh_tokens alter column token_hash type char(64);
+alter table refresh_tokens alter column parent_token_hash type char(64);
+
+update refresh_tokens
+set
+    token_hash = encode(sha256(token_hash::bytea), 'hex'),
+    parent_token_hash = encode(sha256(parent_token_hash::bytea), 'hex');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- NOTE: digests can't be turned back into tokens, so every session has to login again.
+delete from sessions;
+alter table refresh_tokens alter column token_hash type varchar(100);
+alter table refresh_tokens alter column parent_token_hash type varchar(100);
+alter table refresh_tokens rename column token_hash to token;
+alter table refresh_tokens rename column parent_token_hash to parent_token;
+-- +goose StatementEnd
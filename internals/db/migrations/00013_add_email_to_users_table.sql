@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column email varchar(254) unique,
+    add column email_verified_at timestamptz;
+
+create table action_tokens (
+    id uuid,
+    user_id uuid not null,
+    purpose varchar(20) not null check (purpose in ('verify_email', 'reset_password')),
+    email varchar(254) not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    used_at timestamptz,
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table action_tokens;
+alter table users
+    drop column email,
+    drop column email_verified_at;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column totp_secret varchar(64),
+    add column totp_enabled_at timestamptz,
+    add column totp_last_used_step bigint not null default 0;
+
+create table recovery_codes (
+    user_id uuid,
+    code_hash char(64),
+    used_at timestamptz,
+
+    primary key (user_id, code_hash),
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create table two_factor_challenges (
+    id uuid,
+    user_id uuid not null,
+    failed_attempts int not null default 0,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table two_factor_challenges;
+drop table recovery_codes;
+alter table users
+    drop column totp_secret,
+    drop column totp_enabled_at,
+    drop column totp_last_used_step;
+-- +goose StatementEnd
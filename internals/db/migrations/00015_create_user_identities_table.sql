@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+create table user_identities (
+    provider varchar(50),
+    subject varchar(255),
+    user_id uuid not null,
+    email varchar(254),
+    created_at timestamptz not null default now(),
+
+    primary key (provider, subject),
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create index on user_identities(user_id);
+
+create table oidc_login_states (
+    state varchar(64),
+    provider varchar(50) not null,
+    nonce varchar(64) not null,
+    code_verifier varchar(128) not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (state)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table oidc_login_states;
+drop table user_identities;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table personal_access_tokens (
+    id uuid,
+    user_id uuid not null,
+    name varchar(100) not null,
+    token_hash char(64) not null unique,
+    scopes varchar[] not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz,
+    last_used_at timestamptz,
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create index on personal_access_tokens(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table personal_access_tokens;
+-- +goose StatementEnd
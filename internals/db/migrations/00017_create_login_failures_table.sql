@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: failures are tracked by key, which is either 'user:<username>' or 'ip:<address>'.
+-- usernames are tracked whether they exist or not, so locking out doesn't reveal who has an account.
+create table login_failures (
+    key varchar(100),
+    failed_attempts int not null,
+    last_failed_at timestamptz not null,
+    locked_until timestamptz,
+
+    primary key (key)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column role varchar(20) not null default 'user' check (role in ('user', 'moderator', 'admin'));
+
+create table moderation_actions (
+    id int generated always as identity,
+    actor_id uuid not null,
+    action varchar(50) not null,
+    target_id uuid not null,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (actor_id) references users (id)
+);
+
+create index on moderation_actions(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table moderation_actions;
+alter table users drop column role;
+-- +goose StatementEnd
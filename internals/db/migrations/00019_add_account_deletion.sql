@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column deletion_scheduled_at timestamptz;
+
+create index on users(deletion_scheduled_at) where deletion_scheduled_at is not null;
+
+-- NOTE: posts and comments of deleted users are reassigned to this user, the username
+-- doesn't pass the username validation so no one can register it.
+insert into users (id, name, username, hashed_password)
+values ('00000000-0000-0000-0000-000000000000', 'Deleted User', '[deleted]', '');
+
+alter table comment_votes
+    drop constraint comment_votes_user_id_fkey,
+    add foreign key (user_id) references users (id) on delete cascade;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table comment_votes
+    drop constraint comment_votes_user_id_fkey,
+    add foreign key (user_id) references users (id);
+
+delete from users where id = '00000000-0000-0000-0000-000000000000';
+
+alter table users drop column deletion_scheduled_at;
+-- +goose StatementEnd
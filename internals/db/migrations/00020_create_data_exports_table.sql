@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+create table data_exports (
+    id uuid default gen_random_uuid(),
+    user_id uuid not null,
+    status varchar(20) not null default 'pending' check (status in ('pending', 'ready', 'failed')),
+    file_path varchar,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz,
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade
+);
+
+create index on data_exports(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table data_exports;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column avatar_key varchar(200);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users drop column avatar_key;
+-- +goose StatementEnd
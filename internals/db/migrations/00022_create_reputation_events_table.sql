@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column reputation int not null default 0;
+
+-- NOTE: rows are never updated or deleted (except with their user), undoing an event appends
+-- its reversal. users.reputation is the sum of the user's events points.
+create table reputation_events (
+    id bigint generated always as identity,
+    user_id uuid not null,
+    actor_id uuid,
+    kind varchar(50) not null,
+    source_id uuid not null,
+    points int not null,
+    reverses_id bigint unique,
+    created_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade,
+    foreign key (actor_id) references users (id) on delete set null,
+    foreign key (reverses_id) references reputation_events (id) on delete cascade
+);
+
+create index on reputation_events(user_id, created_at);
+create index on reputation_events(source_id, kind);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table reputation_events;
+alter table users drop column reputation;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: the badge definitions live in the code, source_key is what makes an award unique: empty for
+-- badges awarded once per user, or the id of the content or the tag name for repeatable ones.
+create table user_badges (
+    id bigint generated always as identity,
+    user_id uuid not null,
+    badge varchar(50) not null,
+    source_id uuid,
+    source_key varchar(100) not null default '',
+    awarded_at timestamptz not null default now(),
+
+    primary key (id),
+    foreign key (user_id) references users (id) on delete cascade,
+    unique (user_id, badge, source_key)
+);
+
+create index on user_badges(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table user_badges;
+-- +goose StatementEnd
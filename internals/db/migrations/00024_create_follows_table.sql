@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table follows (
+    follower_id uuid,
+    followee_id uuid,
+    created_at timestamptz not null default now(),
+
+    primary key (follower_id, followee_id),
+    foreign key (follower_id) references users (id) on delete cascade,
+    foreign key (followee_id) references users (id) on delete cascade,
+    check (follower_id != followee_id)
+);
+
+create index on follows(followee_id);
+create index on posts(user_id, created_at);
+create index on comments(user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index posts_user_id_created_at_idx;
+drop index comments_user_id_created_at_idx;
+drop table follows;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: muted users are only hidden from the muter, blocked users are also stopped from
+-- commenting on or voting on the blocker's content.
+create table user_blocks (
+    blocker_id uuid,
+    blocked_id uuid,
+    kind varchar(10) not null check (kind in ('block', 'mute')),
+    created_at timestamptz not null default now(),
+
+    primary key (blocker_id, blocked_id),
+    foreign key (blocker_id) references users (id) on delete cascade,
+    foreign key (blocked_id) references users (id) on delete cascade,
+    check (blocker_id != blocked_id)
+);
+
+create index on user_blocks(blocked_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table user_blocks;
+-- +goose StatementEnd
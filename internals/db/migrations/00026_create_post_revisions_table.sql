@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: every version of a post is a revision, the first one is the post as it was created.
+create table post_revisions (
+    post_id uuid,
+    revision int,
+    user_id uuid not null,
+    title varchar(200) not null,
+    content varchar not null,
+    edit_summary varchar(300),
+    created_at timestamptz not null default now(),
+
+    primary key (post_id, revision),
+    foreign key (post_id) references posts (id) on delete cascade,
+    foreign key (user_id) references users (id)
+);
+
+insert into post_revisions (post_id, revision, user_id, title, content, created_at)
+select id, 1, user_id, title, content, created_at
+from posts;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table post_revisions;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table comments add column edited_at timestamptz;
+
+-- NOTE: existing answers are treated as accepted now, we don't know when they were.
+alter table post_answers add column accepted_at timestamptz not null default now();
+
+-- NOTE: every version of a comment is a revision, the first one is the comment as it was created.
+create table comment_revisions (
+    comment_id uuid,
+    revision int,
+    user_id uuid not null,
+    content varchar not null,
+    created_at timestamptz not null default now(),
+
+    primary key (comment_id, revision),
+    foreign key (comment_id) references comments (id) on delete cascade,
+    foreign key (user_id) references users (id)
+);
+
+insert into comment_revisions (comment_id, revision, user_id, content, created_at)
+select id, 1, user_id, content, created_at
+from comments;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table comment_revisions;
+alter table post_answers drop column accepted_at;
+alter table comments drop column edited_at;
+-- +goose StatementEnd
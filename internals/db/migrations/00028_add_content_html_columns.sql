@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: content_html is the rendered markdown of content, it's rendered by the app on write.
+-- Rows rendered by another version of the renderer, like the existing ones (version 0), are picked up by cmd/rerender.
+alter table posts
+    add column content_html varchar not null default '',
+    add column content_html_version int not null default 0;
+
+alter table comments
+    add column content_html varchar not null default '',
+    add column content_html_version int not null default 0;
+
+create index on posts(content_html_version);
+create index on comments(content_html_version);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table comments
+    drop column content_html,
+    drop column content_html_version;
+
+alter table posts
+    drop column content_html,
+    drop column content_html_version;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table post_votes (
+    post_id uuid,
+    user_id uuid,
+    kind varchar(10) not null check (kind in ('up', 'down')),
+
+    primary key (post_id, user_id),
+    foreign key (post_id) references posts (id) on delete cascade,
+    foreign key (user_id) references users (id)
+);
+
+-- NOTE: the counts are kept in sync with post_votes by the vote handlers, in the same tx as the vote.
+alter table posts
+    add column up_count int not null default 0,
+    add column down_count int not null default 0,
+    add column score int not null generated always as (up_count - down_count) stored;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table posts
+    drop column score,
+    drop column up_count,
+    drop column down_count;
+
+drop table post_votes;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NOTE: the counts are kept in sync with comment_votes by the vote handlers, in the same tx as the vote.
+-- cmd/reconcile recomputes them if they ever drift.
+alter table comments
+    add column up_count int not null default 0,
+    add column down_count int not null default 0,
+    add column score int not null generated always as (up_count - down_count) stored;
+
+update comments c
+set
+    up_count = v.up_count,
+    down_count = v.down_count
+from (
+    select
+        comment_id,
+        count(*) filter (where kind = 'up')::int as up_count,
+        count(*) filter (where kind = 'down')::int as down_count
+    from comment_votes
+    group by comment_id
+) v
+where c.id = v.comment_id;
+
+create index on comments(post_id, score desc, created_at desc);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table comments
+    drop column score,
+    drop column up_count,
+    drop column down_count;
+-- +goose StatementEnd
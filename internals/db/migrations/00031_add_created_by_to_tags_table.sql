@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table tags
+    add column created_by uuid references users (id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table tags drop column created_by;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users drop constraint users_email_key;
+
+create unique index users_verified_email_key on users(email) where email_verified_at is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index users_verified_email_key;
+
+alter table users add constraint users_email_key unique (email);
+-- +goose StatementEnd
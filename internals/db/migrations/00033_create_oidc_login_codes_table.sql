@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+create table oidc_login_codes (
+    code_hash varchar(64),
+    user_id uuid not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+
+    primary key (code_hash),
+    foreign key (user_id) references users (id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table oidc_login_codes;
+-- +goose StatementEnd
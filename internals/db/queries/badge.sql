@@ -0,0 +1,62 @@
+-- name: InsertUserBadge :execrows
+insert into user_badges (user_id, badge, source_id, source_key)
+values ($1, $2, $3, $4)
+on conflict (user_id, badge, source_key) do nothing;
+
+-- name: GetUserBadges :many
+select *
+from user_badges
+where
+    user_id = $1 and
+    id <= coalesce(nullif(sqlc.arg(id)::bigint, 0), 9223372036854775807)
+order by id desc
+limit $2;
+
+-- name: GetRecentBadgeAwards :many
+select ub.*, u.username
+from user_badges ub
+join users u on u.id = ub.user_id
+where ub.id <= coalesce(nullif(sqlc.arg(id)::bigint, 0), 9223372036854775807)
+order by ub.id desc
+limit $1;
+
+-- name: GetFirstPostBadgeCandidates :many
+select distinct on (p.user_id) p.user_id, p.id as source_id
+from posts p
+where not exists (select 1 from user_badges ub where ub.user_id = p.user_id and ub.badge = sqlc.arg(badge))
+order by p.user_id, p.created_at;
+
+-- name: GetFirstAcceptedAnswerBadgeCandidates :many
+select distinct on (c.user_id) c.user_id, c.id as source_id
+from post_answers pa
+join comments c on c.id = pa.comment_id
+join posts p on p.id = pa.post_id
+where
+    c.user_id != p.user_id and
+    not exists (select 1 from user_badges ub where ub.user_id = c.user_id and ub.badge = sqlc.arg(badge))
+order by c.user_id, c.created_at;
+
+-- name: GetCommentUpvotesBadgeCandidates :many
+select c.user_id, c.id as source_id
+from comments c
+join comment_votes cv on cv.comment_id = c.id
+where
+    cv.kind = 'up' and
+    not exists (
+        select 1 from user_badges ub
+        where ub.user_id = c.user_id and ub.badge = sqlc.arg(badge) and ub.source_key = c.id::varchar
+    )
+group by c.id
+having count(*) >= sqlc.arg(min_upvotes)::int;
+
+-- name: GetTagPostsBadgeCandidates :many
+select p.user_id, t.name as tag
+from posts p
+join post_tags pt on pt.post_id = p.id
+join tags t on t.id = pt.tag_id
+where not exists (
+    select 1 from user_badges ub
+    where ub.user_id = p.user_id and ub.badge = sqlc.arg(badge) and ub.source_key = t.name
+)
+group by p.user_id, t.name
+having count(*) >= sqlc.arg(min_posts)::int;
@@ -0,0 +1,48 @@
+-- name: UpsertUserBlock :exec
+insert into user_blocks (blocker_id, blocked_id, kind)
+values ($1, $2, $3)
+on conflict (blocker_id, blocked_id) do update
+    set kind = excluded.kind, created_at = now();
+
+-- name: DeleteUserBlock :execrows
+delete from user_blocks where blocker_id = $1 and blocked_id = $2 and kind = $3;
+
+-- name: GetUserBlocks :many
+select sqlc.embed(u), b.created_at as blocked_at
+from user_blocks b
+join users u on u.id = b.blocked_id
+where
+    b.blocker_id = $1 and
+    b.kind = $2 and
+    b.created_at <= coalesce(
+        nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by b.created_at desc
+limit $3;
+
+-- name: CheckPostBlockedForUser :one
+select exists (
+    select 1
+    from user_blocks b
+    join posts p on p.user_id = b.blocker_id
+    where p.id = $1 and b.blocked_id = $2 and b.kind = 'block'
+);
+
+-- name: CheckCommentBlockedForUser :one
+select exists (
+    select 1
+    from comments c
+    join posts p on p.id = c.post_id
+    join user_blocks b on b.blocker_id in (c.user_id, p.user_id)
+    where c.id = $1 and b.blocked_id = $2 and b.kind = 'block'
+);
+
+-- name: CheckUsersBlocked :one
+select exists (
+    select 1
+    from user_blocks
+    where
+        kind = 'block' and
+        ((blocker_id = $1 and blocked_id = $2) or (blocker_id = $2 and blocked_id = $1))
+);
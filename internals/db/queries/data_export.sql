@@ -0,0 +1,62 @@
+-- name: InsertDataExport :exec
+insert into data_exports (id, user_id)
+values ($1, $2);
+
+-- name: CheckPendingDataExportForUser :one
+-- NOTE: exports that stayed pending since before pending_since were interrupted by a restart, they don't block new ones.
+select exists (
+    select 1 from data_exports
+    where user_id = $1 and status = 'pending' and created_at > sqlc.arg(pending_since)
+);
+
+-- name: SetDataExportAsReady :exec
+update data_exports
+set
+    status = 'ready',
+    file_path = $1,
+    expires_at = $2
+where id = $3;
+
+-- name: SetDataExportAsFailed :exec
+update data_exports
+set
+    status = 'failed',
+    expires_at = $1
+where id = $2;
+
+-- name: GetDataExportForUser :one
+select * from data_exports where id = $1 and user_id = $2;
+
+-- name: DeleteExpiredDataExports :many
+delete from data_exports
+where
+    expires_at <= now() or
+    (status = 'pending' and created_at <= sqlc.arg(pending_since))
+returning file_path;
+
+-- name: DeleteUserDataExports :many
+delete from data_exports
+where user_id = $1
+returning file_path;
+
+-- name: GetAllUserPosts :many
+select * from posts where user_id = $1 order by created_at;
+
+-- name: GetAllUserComments :many
+select * from comments where user_id = $1 order by created_at;
+
+-- name: GetAllUserCommentVotes :many
+select * from comment_votes where user_id = $1;
+
+-- name: GetAllUserPostVotes :many
+select * from post_votes where user_id = $1;
+
+-- name: GetTagsCreatedByUser :many
+select * from tags where created_by = $1 order by created_at;
+
+-- name: GetUserAcceptedAnswers :many
+select pa.post_id, pa.comment_id, p.user_id = sqlc.arg(user_id) as accepted_by_user
+from post_answers pa
+join posts p on p.id = pa.post_id
+join comments c on c.id = pa.comment_id
+where p.user_id = sqlc.arg(user_id) or c.user_id = sqlc.arg(user_id);
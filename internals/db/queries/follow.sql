@@ -0,0 +1,65 @@
+-- name: InsertFollow :execrows
+insert into follows (follower_id, followee_id)
+values ($1, $2)
+on conflict (follower_id, followee_id) do nothing;
+
+-- name: DeleteFollow :execrows
+delete from follows where follower_id = $1 and followee_id = $2;
+
+-- name: GetUserFollowers :many
+select sqlc.embed(u), f.created_at as followed_at
+from follows f
+join users u on u.id = f.follower_id
+where
+    f.followee_id = $1 and
+    f.created_at <= coalesce(
+        nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by f.created_at desc
+limit $2;
+
+-- name: GetUserFollowing :many
+select sqlc.embed(u), f.created_at as followed_at
+from follows f
+join users u on u.id = f.followee_id
+where
+    f.follower_id = $1 and
+    f.created_at <= coalesce(
+        nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by f.created_at desc
+limit $2;
+
+-- name: GetFollowingFeed :many
+select *
+from (
+    select 'post'::varchar as kind, p.id, p.id as post_id, p.user_id, p.title, p.content, p.content_html, p.created_at
+    from posts p
+    join follows f on f.followee_id = p.user_id
+    where f.follower_id = sqlc.arg(follower_id)
+    union all
+    select 'answer'::varchar as kind, c.id, c.post_id, c.user_id, p.title, c.content, c.content_html, c.created_at
+    from comments c
+    join posts p on p.id = c.post_id
+    join follows f on f.followee_id = c.user_id
+    where f.follower_id = sqlc.arg(follower_id)
+) feed
+where
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = sqlc.arg(follower_id) and b.blocked_id = feed.user_id and b.kind = 'mute'
+    ) and
+    feed.created_at <= coalesce(
+        nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by feed.created_at desc
+limit $1;
+
+-- name: DeleteFollowsBetween :exec
+delete from follows
+where
+    (follower_id = $1 and followee_id = $2) or
+    (follower_id = $2 and followee_id = $1);
@@ -0,0 +1,30 @@
+-- name: InsertOidcLoginState :exec
+insert into oidc_login_states (state, provider, nonce, code_verifier, expires_at)
+values ($1, $2, $3, $4, $5);
+
+-- name: DeleteOidcLoginState :one
+delete from oidc_login_states
+where state = $1 and provider = $2 and expires_at > now()
+returning *;
+
+-- name: DeleteExpiredOidcLoginStates :exec
+delete from oidc_login_states where expires_at <= now();
+
+-- name: InsertOidcLoginCode :exec
+insert into oidc_login_codes (code_hash, user_id, expires_at)
+values ($1, $2, $3);
+
+-- name: DeleteOidcLoginCode :one
+delete from oidc_login_codes
+where code_hash = $1 and expires_at > now()
+returning user_id;
+
+-- name: DeleteExpiredOidcLoginCodes :exec
+delete from oidc_login_codes where expires_at <= now();
+
+-- name: GetUserIdentity :one
+select * from user_identities where provider = $1 and subject = $2;
+
+-- name: InsertUserIdentity :exec
+insert into user_identities (provider, subject, user_id, email)
+values ($1, $2, $3, $4);
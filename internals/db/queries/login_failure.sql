@@ -0,0 +1,26 @@
+-- name: InsertLoginFailure :one
+insert into login_failures (key, failed_attempts, last_failed_at)
+values ($1, 1, now())
+on conflict (key) do update
+    set
+        failed_attempts = case
+            when login_failures.last_failed_at < sqlc.arg(window_start)::timestamptz then 1
+            else login_failures.failed_attempts + 1
+        end,
+        last_failed_at = excluded.last_failed_at
+returning failed_attempts;
+
+-- name: LockLoginFailureKey :exec
+update login_failures
+set locked_until = $1
+where key = $2;
+
+-- name: GetLoginLockedUntil :one
+select locked_until::timestamptz
+from login_failures
+where key = any(sqlc.arg(keys)::varchar[]) and locked_until > now()
+order by locked_until desc
+limit 1;
+
+-- name: DeleteLoginFailures :exec
+delete from login_failures where key = $1;
@@ -0,0 +1,29 @@
+-- name: InsertModerationAction :exec
+insert into moderation_actions (actor_id, action, target_id)
+values ($1, $2, $3);
+
+-- name: GetModerationActions :many
+select *
+from moderation_actions
+where
+    created_at <= coalesce(
+        nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by created_at desc
+limit $1;
+
+-- name: UpdateUserRole :execrows
+update users
+set role = $1
+where id = $2;
+
+-- name: ReassignUserModerationActions :exec
+update moderation_actions
+set actor_id = sqlc.arg(to_user_id)
+where actor_id = sqlc.arg(from_user_id);
+
+-- name: PromoteUsersToAdmin :execrows
+update users
+set role = 'admin'
+where id = any(sqlc.arg(ids)::uuid[]) and role <> 'admin';
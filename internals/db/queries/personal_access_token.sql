@@ -0,0 +1,28 @@
+-- name: InsertPersonalAccessToken :one
+insert into personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: GetPersonalAccessTokenByHash :one
+select pat.*, u.role
+from personal_access_tokens pat
+join users u on u.id = pat.user_id
+where
+    pat.token_hash = $1 and
+    (pat.expires_at is null or pat.expires_at > now());
+
+-- name: UpdatePersonalAccessTokenLastUsed :exec
+update personal_access_tokens
+set last_used_at = now()
+where id = $1;
+
+-- name: GetUserPersonalAccessTokens :many
+select * from personal_access_tokens
+where user_id = $1
+order by created_at desc;
+
+-- name: CheckPersonalAccessTokenForUser :one
+select exists (select 1 from personal_access_tokens where id = $1 and user_id = $2 for update);
+
+-- name: DeletePersonalAccessToken :exec
+delete from personal_access_tokens where id = $1;
@@ -1,6 +1,6 @@
 -- name: InsertPost :one
-insert into posts (id, user_id, title, content)
-values ($1, $2, $3, $4)
+insert into posts (id, user_id, title, content, content_html, content_html_version)
+values ($1, $2, $3, $4, $5, $6)
 returning *;
 
 -- name: GetPostByID :one
@@ -13,16 +13,18 @@ select exists (select 1 from posts where id = $1 and user_id = $2 for update);
 update posts
 set
     title = $1,
-    content = $2
-where id = $3;
+    content = $2,
+    content_html = $3,
+    content_html_version = $4
+where id = $5;
 
 -- name: DeletePostByID :exec
 delete from posts where id = $1;
 
 -- name: InsertTag :one
 with new_tag as (
-    insert into tags (name) 
-    values ($1)
+    insert into tags (name, created_by)
+    values ($1, $2)
     on conflict (name) do nothing
     returning id
 )
@@ -75,6 +77,10 @@ where
     (
         sqlc.arg(query)::varchar = '' or
         to_tsvector('english', p.title || ' ' || p.content) @@ to_tsquery(sqlc.arg(query)::varchar)
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = sqlc.arg(viewer_id) and b.blocked_id = p.user_id
     )
 order by p.created_at desc
 limit $1;
@@ -83,16 +89,19 @@ limit $1;
 select exists (select 1 from posts where id = $1 for update);
 
 -- name: InsertComment :exec
-insert into comments (id, post_id, user_id, content)
-values ($1, $2, $3, $4);
+insert into comments (id, post_id, user_id, content, content_html, content_html_version)
+values ($1, $2, $3, $4, $5, $6);
 
 -- name: CheckCommentForUser :one
 select exists (select 1 from comments where id = $1 and user_id = $2 for update);
 
 -- name: UpdateComment :exec
 update comments
-set content = $1
-where id = $2;
+set content = $1, content_html = $2, content_html_version = $3, edited_at = now()
+where id = $4;
+
+-- name: GetPostCommentIDs :many
+select id from comments where post_id = $1;
 
 -- name: DeleteComment :exec
 delete from comments where id = $1;
@@ -104,10 +113,35 @@ where
     created_at <= coalesce(
         nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
         now()::timestamptz
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = sqlc.arg(viewer_id) and b.blocked_id = comments.user_id
     )
 order by created_at desc
 limit $2;
 
+-- name: GetPostCommentsByScore :many
+select * from comments
+where
+    post_id = $1 and
+    (score, created_at) <= (
+        case
+            when sqlc.arg(created_at)::timestamptz = '0001-01-01 00:00:00'::timestamptz then 2147483647
+            else sqlc.arg(score)::int
+        end,
+        coalesce(
+            nullif(sqlc.arg(created_at)::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+            now()::timestamptz
+        )
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = sqlc.arg(viewer_id) and b.blocked_id = comments.user_id
+    )
+order by score desc, created_at desc
+limit $2;
+
 -- name: CheckComment :one
 select exists (select 1 from comments where id = $1 for update);
 
@@ -124,23 +158,90 @@ select exists (select 1 from comment_votes where comment_id = $1 and user_id = $
 delete from comment_votes where comment_id = $1 and user_id = $2;
 
 -- name: GetCommentVoteCounts :one
-select 
-    sum(case when kind = 'up' then 1 else 0 end) over () as up_count,
-    sum(case when kind = 'down' then 1 else 0 end) over () as down_count
-from comment_votes
-where comment_id = $1;
+select up_count, down_count, score from comments where id = $1;
+
+-- name: AddCommentVoteCounts :exec
+update comments
+set
+    up_count = up_count + sqlc.arg(up_delta),
+    down_count = down_count + sqlc.arg(down_delta)
+where id = sqlc.arg(id);
+
+-- name: GetPostVote :one
+select * from post_votes where post_id = $1 and user_id = $2 for update;
+
+-- name: InsertPostVote :exec
+insert into post_votes (post_id, user_id, kind)
+values ($1, $2, $3)
+on conflict (post_id, user_id) do update
+    set kind = excluded.kind;
+
+-- name: DeletePostVote :exec
+delete from post_votes where post_id = $1 and user_id = $2;
+
+-- name: AddPostVoteCounts :exec
+update posts
+set
+    up_count = up_count + sqlc.arg(up_delta),
+    down_count = down_count + sqlc.arg(down_delta)
+where id = sqlc.arg(id);
+
+-- name: GetPostVotesForUser :many
+select post_id, kind from post_votes
+where user_id = $1 and post_id = any(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetPostAuthor :one
+select user_id from posts where id = $1;
 
 -- name: InsertPostAnswer :exec
 insert into post_answers (post_id, comment_id)
 values ($1, $2)
 on conflict (post_id) do update
-    set comment_id = excluded.comment_id;
+    set comment_id = excluded.comment_id, accepted_at = now();
 
 -- name: DeletePostAnswer :exec
 delete from post_answers where post_id = $1;
 
 -- name: GetPostAnswer :one
-select c.*
+select c.*, pa.accepted_at
 from post_answers pa
 join comments c on c.id = pa.comment_id
 where pa.post_id = $1;
+
+-- name: GetCommentAuthor :one
+select user_id from comments where id = $1;
+
+-- name: GetAnswerPostOwner :one
+select p.id as post_id, p.title, u.id as user_id, u.email, u.email_verified_at
+from post_answers pa
+join posts p on p.id = pa.post_id
+join users u on u.id = p.user_id
+where pa.comment_id = $1;
+
+-- name: GetCommentVote :one
+select * from comment_votes where comment_id = $1 and user_id = $2 for update;
+
+-- name: CheckTag :one
+select exists (select 1 from tags where name = $1);
+
+-- name: GetStalePostsContent :many
+select id, content from posts
+where content_html_version <> $1
+limit $2
+for update skip locked;
+
+-- name: UpdatePostContentHtml :exec
+update posts
+set content_html = $1, content_html_version = $2
+where id = $3;
+
+-- name: GetStaleCommentsContent :many
+select id, content from comments
+where content_html_version <> $1
+limit $2
+for update skip locked;
+
+-- name: UpdateCommentContentHtml :exec
+update comments
+set content_html = $1, content_html_version = $2
+where id = $3;
@@ -0,0 +1,47 @@
+-- name: LockPostsBatch :many
+select id from posts
+where id > $1
+order by id
+limit $2
+for update;
+
+-- name: ReconcilePostVoteCounts :execrows
+update posts p
+set
+    up_count = v.up_count,
+    down_count = v.down_count
+from (
+    select
+        p2.id,
+        count(pv.kind) filter (where pv.kind = 'up')::int as up_count,
+        count(pv.kind) filter (where pv.kind = 'down')::int as down_count
+    from posts p2
+    left join post_votes pv on pv.post_id = p2.id
+    where p2.id = any(sqlc.arg(ids)::uuid[])
+    group by p2.id
+) v
+where p.id = v.id and (p.up_count <> v.up_count or p.down_count <> v.down_count);
+
+-- name: LockCommentsBatch :many
+select id from comments
+where id > $1
+order by id
+limit $2
+for update;
+
+-- name: ReconcileCommentVoteCounts :execrows
+update comments c
+set
+    up_count = v.up_count,
+    down_count = v.down_count
+from (
+    select
+        c2.id,
+        count(cv.kind) filter (where cv.kind = 'up')::int as up_count,
+        count(cv.kind) filter (where cv.kind = 'down')::int as down_count
+    from comments c2
+    left join comment_votes cv on cv.comment_id = c2.id
+    where c2.id = any(sqlc.arg(ids)::uuid[])
+    group by c2.id
+) v
+where c.id = v.id and (c.up_count <> v.up_count or c.down_count <> v.down_count);
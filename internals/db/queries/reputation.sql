@@ -0,0 +1,58 @@
+-- name: InsertReputationEvent :exec
+insert into reputation_events (user_id, actor_id, kind, source_id, points, reverses_id)
+values ($1, $2, $3, $4, $5, $6);
+
+-- name: AddUserReputation :exec
+update users
+set reputation = reputation + $1
+where id = $2;
+
+-- name: GetUserReputationSince :one
+select coalesce(sum(e.points), 0)::int
+from reputation_events e
+where
+    e.user_id = $1 and
+    e.kind = any(sqlc.arg(kinds)::varchar[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id) and
+    e.created_at >= sqlc.arg(since);
+
+-- name: GetActiveReputationEvent :one
+select e.*
+from reputation_events e
+where
+    e.kind = $1 and
+    e.source_id = $2 and
+    e.actor_id = $3 and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id desc
+limit 1;
+
+-- name: GetActiveReputationEventsBySources :many
+select e.*
+from reputation_events e
+where
+    e.source_id = any(sqlc.arg(source_ids)::uuid[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id;
+
+-- name: GetActiveReputationEventsByActor :many
+select e.*
+from reputation_events e
+where
+    e.actor_id = $1 and
+    e.kind = any(sqlc.arg(kinds)::varchar[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id;
+
+-- name: GetUserReputationEvents :many
+select *
+from reputation_events
+where
+    user_id = $1 and
+    id <= coalesce(nullif(sqlc.arg(id)::bigint, 0), 9223372036854775807)
+order by id desc
+limit $2;
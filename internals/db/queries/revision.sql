@@ -0,0 +1,51 @@
+-- name: InsertPostRevision :one
+insert into post_revisions (post_id, revision, user_id, title, content, edit_summary)
+values (
+    sqlc.arg(post_id),
+    (select coalesce(max(revision), 0) + 1 from post_revisions where post_id = sqlc.arg(post_id)),
+    sqlc.arg(user_id),
+    sqlc.arg(title),
+    sqlc.arg(content),
+    sqlc.arg(edit_summary)
+)
+returning *;
+
+-- name: GetPostRevision :one
+select * from post_revisions where post_id = $1 and revision = $2;
+
+-- name: GetPostRevisions :many
+select *
+from post_revisions
+where
+    post_id = $1 and
+    revision <= coalesce(nullif(sqlc.arg(revision)::int, 0), 2147483647)
+order by revision desc
+limit $2;
+
+-- name: ReassignUserPostRevisions :exec
+update post_revisions
+set user_id = sqlc.arg(to_user_id)
+where user_id = sqlc.arg(from_user_id);
+
+-- name: InsertCommentRevision :exec
+insert into comment_revisions (comment_id, revision, user_id, content)
+values (
+    sqlc.arg(comment_id),
+    (select coalesce(max(revision), 0) + 1 from comment_revisions where comment_id = sqlc.arg(comment_id)),
+    sqlc.arg(user_id),
+    sqlc.arg(content)
+);
+
+-- name: GetCommentRevisions :many
+select *
+from comment_revisions
+where
+    comment_id = $1 and
+    revision <= coalesce(nullif(sqlc.arg(revision)::int, 0), 2147483647)
+order by revision desc
+limit $2;
+
+-- name: ReassignUserCommentRevisions :exec
+update comment_revisions
+set user_id = sqlc.arg(to_user_id)
+where user_id = sqlc.arg(from_user_id);
@@ -0,0 +1,31 @@
+-- name: InsertSession :exec
+insert into sessions (id, user_id, user_agent, ip_address)
+values ($1, $2, $3, $4);
+
+-- name: CheckSessionForUser :one
+select exists (select 1 from sessions where id = $1 and user_id = $2 for update);
+
+-- name: GetUserSessions :many
+select
+    s.id,
+    s.user_agent,
+    s.ip_address,
+    s.created_at,
+    rt.created_at as refreshed_at,
+    rt.expires_at
+from sessions s
+join refresh_tokens rt on rt.family_id = s.id
+where
+    s.user_id = $1 and
+    rt.used_at is null and
+    rt.expires_at > now()
+order by s.created_at desc;
+
+-- name: DeleteSession :exec
+delete from sessions where id = $1;
+
+-- name: DeleteUserSessions :exec
+delete from sessions where user_id = $1;
+
+-- name: DeleteUserSessionsExcept :exec
+delete from sessions where user_id = $1 and id != $2;
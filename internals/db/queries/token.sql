@@ -1,6 +1,28 @@
 -- name: InsertRefreshToken :exec
-insert into refresh_tokens (token, user_id, expires_at)
-values ($1, $2, $3);
+insert into refresh_tokens (token_hash, user_id, family_id, parent_token_hash, expires_at)
+values ($1, $2, $3, $4, $5);
 
 -- name: GetRefreshToken :one
-select * from refresh_tokens where token = $1;
+select * from refresh_tokens where token_hash = $1 for update;
+
+-- name: MarkRefreshTokenAsUsed :exec
+update refresh_tokens
+set used_at = now()
+where token_hash = $1;
+
+-- name: InsertActionToken :exec
+insert into action_tokens (id, user_id, purpose, email, expires_at)
+values ($1, $2, $3, $4, $5);
+
+-- name: UseActionToken :one
+update action_tokens
+set used_at = now()
+where
+    id = $1 and
+    purpose = $2 and
+    used_at is null and
+    expires_at > now()
+returning *;
+
+-- name: DeleteExpiredActionTokens :exec
+delete from action_tokens where expires_at <= now();
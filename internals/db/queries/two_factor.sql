@@ -0,0 +1,56 @@
+-- name: SetUserTotpSecret :exec
+update users
+set
+    totp_secret = $1,
+    totp_enabled_at = null
+where id = $2;
+
+-- name: EnableUserTotp :exec
+update users
+set
+    totp_enabled_at = now(),
+    totp_last_used_step = $1
+where id = $2;
+
+-- name: DisableUserTotp :exec
+update users
+set
+    totp_secret = null,
+    totp_enabled_at = null,
+    totp_last_used_step = 0
+where id = $1;
+
+-- name: UpdateUserTotpLastUsedStep :exec
+update users
+set totp_last_used_step = $1
+where id = $2;
+
+-- name: InsertRecoveryCode :exec
+insert into recovery_codes (user_id, code_hash)
+values ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+update recovery_codes
+set used_at = now()
+where user_id = $1 and code_hash = $2 and used_at is null;
+
+-- name: DeleteUserRecoveryCodes :exec
+delete from recovery_codes where user_id = $1;
+
+-- name: InsertTwoFactorChallenge :exec
+insert into two_factor_challenges (id, user_id, expires_at)
+values ($1, $2, $3);
+
+-- name: GetTwoFactorChallenge :one
+select * from two_factor_challenges where id = $1 and expires_at > now() for update;
+
+-- name: IncrementTwoFactorChallengeFailedAttempts :exec
+update two_factor_challenges
+set failed_attempts = failed_attempts + 1
+where id = $1;
+
+-- name: DeleteTwoFactorChallenge :exec
+delete from two_factor_challenges where id = $1;
+
+-- name: DeleteExpiredTwoFactorChallenges :exec
+delete from two_factor_challenges where expires_at <= now();
@@ -21,11 +21,130 @@ select exists (select 1 from users where username = $1 and id != $2 for update);
 -- name: UpdateUserByID :exec
 update users
 set
-    name = $1,
-    bio = $2,
-    username = $3,
-    hashed_password = $4
-where id = $5;
+    name = coalesce(sqlc.narg(name), name),
+    bio = case when sqlc.arg(set_bio)::bool then sqlc.narg(bio) else bio end,
+    username = coalesce(sqlc.narg(username), username)
+where id = sqlc.arg(id);
+
+-- name: UpdateUserPassword :exec
+update users
+set hashed_password = $1
+where id = $2;
 
 -- name: DeleteUserById :exec
 delete from users where id = $1;
+
+-- name: CheckVerifiedEmailExceptUserID :one
+select exists (select 1 from users where email = $1 and email_verified_at is not null and id != $2 for update);
+
+-- name: UpdateUserEmail :exec
+update users
+set
+    email = $1,
+    email_verified_at = null
+where id = $2;
+
+-- name: SetUserEmailAsVerified :execrows
+update users
+set email_verified_at = now()
+where
+    users.id = $1 and
+    users.email = $2 and
+    not exists (select 1 from users u where u.email = $2 and u.email_verified_at is not null and u.id != $1);
+
+-- name: GetUserByVerifiedEmail :one
+select * from users where email = $1 and email_verified_at is not null;
+
+-- name: ScheduleUserDeletion :one
+update users
+set deletion_scheduled_at = coalesce(deletion_scheduled_at, $1)
+where id = $2
+returning deletion_scheduled_at;
+
+-- name: CancelUserDeletion :execrows
+update users
+set deletion_scheduled_at = null
+where id = $1 and deletion_scheduled_at is not null;
+
+-- name: GetUsersDueForDeletion :many
+select id, username, avatar_key
+from users
+where deletion_scheduled_at <= now()
+order by deletion_scheduled_at
+limit $1
+for update skip locked;
+
+-- name: ReassignUserPosts :exec
+update posts
+set user_id = sqlc.arg(to_user_id)
+where user_id = sqlc.arg(from_user_id);
+
+-- name: ReassignUserComments :exec
+update comments
+set user_id = sqlc.arg(to_user_id)
+where user_id = sqlc.arg(from_user_id);
+
+-- name: DeleteUserCommentVotes :exec
+with deleted_votes as (
+    delete from comment_votes where comment_votes.user_id = $1
+    returning comment_id, kind
+)
+update comments c
+set
+    up_count = c.up_count - v.up_count,
+    down_count = c.down_count - v.down_count
+from (
+    select
+        comment_id,
+        count(*) filter (where kind = 'up')::int as up_count,
+        count(*) filter (where kind = 'down')::int as down_count
+    from deleted_votes
+    group by comment_id
+) v
+where c.id = v.comment_id;
+
+-- name: DeleteUserPostVotes :exec
+with deleted_votes as (
+    delete from post_votes where post_votes.user_id = $1
+    returning post_id, kind
+)
+update posts p
+set
+    up_count = p.up_count - v.up_count,
+    down_count = p.down_count - v.down_count
+from (
+    select
+        post_id,
+        count(*) filter (where kind = 'up')::int as up_count,
+        count(*) filter (where kind = 'down')::int as down_count
+    from deleted_votes
+    group by post_id
+) v
+where p.id = v.post_id;
+
+-- name: GetUserAvatarKey :one
+select avatar_key from users where id = $1 for update;
+
+-- name: UpdateUserAvatarKey :exec
+update users
+set avatar_key = $1
+where id = $2;
+
+-- name: GetUserActivityStats :one
+select
+    u.created_at,
+    (
+        select count(*)
+        from post_answers pa
+        join comments c on c.id = pa.comment_id
+        join posts p on p.id = pa.post_id
+        where c.user_id = u.id and p.user_id != u.id
+    )::int as accepted_answers,
+    (
+        select coalesce(sum(case when cv.kind = 'up' then 1 else -1 end), 0)
+        from comment_votes cv
+        join comments c on c.id = cv.comment_id
+        where c.user_id = u.id and cv.user_id != u.id
+    )::int as net_upvotes
+from users u
+where u.id = $1;
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/mail"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DeletedUserID is the placeholder user that owns the posts and comments of deleted users.
+var DeletedUserID = uuid.MustParse("00000000-0000-0000-0000-000000000000")
+
+func HandleDeleteUser(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	// NOTE: scheduling an already scheduled deletion keeps the original date.
+	deletionScheduledAt, err := qtx.ScheduleUserDeletion(context.Background(), repository.ScheduleUserDeletionParams{
+		DeletionScheduledAt: sql.NullTime{Time: time.Now().Add(AccountDeletionGracePeriodDays * 24 * time.Hour), Valid: true},
+		ID:                  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("error scheduling user deletion: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	if repoUser.EmailVerifiedAt.Valid {
+		sendMail(mail.Message{
+			To:      repoUser.Email.String,
+			Subject: "Your iWonder account will be deleted",
+			Body: fmt.Sprintf("Your iWonder account '%s' will be deleted on %s.\n\n"+
+				"Your posts and comments will stay, but they won't be linked to you anymore.\n"+
+				"If you changed your mind, log in before then and cancel the deletion from your account settings:\n%s",
+				repoUser.Username, deletionScheduledAt.Time.UTC().Format(time.RFC1123), os.Getenv("APP_URL")),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"deletionScheduledAt": deletionScheduledAt.Time,
+	})
+}
+
+func HandleCancelUserDeletion(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+	if affectedRows, err := queries.CancelUserDeletion(context.Background(), userID); err != nil {
+		return fmt.Errorf("error canceling user deletion: %v", err)
+	} else if affectedRows == 0 {
+		return c.Status(fiber.StatusNotFound).SendString("no deletion is scheduled")
+	}
+	return c.Status(fiber.StatusOK).SendString("deletion canceled successfully")
+}
+
+// DeleteDueUsers deletes up to limit users whose deletion grace period is over, and returns how many were deleted.
+// Every user is deleted in its own transaction, one that fails is logged and retried on the next run.
+func DeleteDueUsers(limit int) (int, error) {
+	repoUsers, err := queries.GetUsersDueForDeletion(context.Background(), int32(limit))
+	if err != nil {
+		return 0, fmt.Errorf("error getting users due for deletion: %v", err)
+	}
+
+	deletedCount := 0
+	for _, repoUser := range repoUsers {
+		if err := deleteDueUser(repoUser); err != nil {
+			slog.Error("error deleting due user", "userID", repoUser.ID, "err", err)
+			continue
+		}
+		deletedCount++
+	}
+
+	return deletedCount, nil
+}
+
+func deleteDueUser(repoUser repository.GetUsersDueForDeletionRow) error {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	filePaths, err := deleteUser(qtx, repoUser.ID, repoUser.Username)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	// NOTE: files can't be rolled back, so they are only removed once the deletion is committed.
+	removeDataExportFiles(filePaths)
+	if repoUser.AvatarKey.Valid {
+		deleteAvatarFiles(repoUser.AvatarKey.String)
+	}
+
+	return nil
+}
+
+// deleteUser keeps the user's posts and comments under the deleted user placeholder and removes everything else.
+// Sessions, tokens, identities and 2fa data are removed by the cascading foreign keys. It returns the paths of the
+// user's data export files, which are left for the caller to remove after the commit.
+func deleteUser(qtx *repository.Queries, userID uuid.UUID, username string) ([]sql.NullString, error) {
+	if err := qtx.ReassignUserPosts(context.Background(), repository.ReassignUserPostsParams{
+		ToUserID:   DeletedUserID,
+		FromUserID: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("error reassigning posts: %v", err)
+	}
+
+	if err := qtx.ReassignUserComments(context.Background(), repository.ReassignUserCommentsParams{
+		ToUserID:   DeletedUserID,
+		FromUserID: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("error reassigning comments: %v", err)
+	}
+
+	if err := qtx.ReassignUserPostRevisions(context.Background(), repository.ReassignUserPostRevisionsParams{
+		ToUserID:   DeletedUserID,
+		FromUserID: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("error reassigning post revisions: %v", err)
+	}
+
+	if err := qtx.ReassignUserCommentRevisions(context.Background(), repository.ReassignUserCommentRevisionsParams{
+		ToUserID:   DeletedUserID,
+		FromUserID: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("error reassigning comment revisions: %v", err)
+	}
+
+	if err := qtx.ReassignUserModerationActions(context.Background(), repository.ReassignUserModerationActionsParams{
+		ToUserID:   DeletedUserID,
+		FromUserID: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("error reassigning moderation actions: %v", err)
+	}
+
+	// NOTE: the votes go away with the account, and so does the reputation they gave.
+	if err := revokeActorVotesReputation(qtx, userID); err != nil {
+		return nil, fmt.Errorf("error revoking vote reputation: %v", err)
+	}
+
+	if err := qtx.DeleteUserCommentVotes(context.Background(), userID); err != nil {
+		return nil, fmt.Errorf("error deleting comment votes: %v", err)
+	}
+
+	if err := qtx.DeleteUserPostVotes(context.Background(), userID); err != nil {
+		return nil, fmt.Errorf("error deleting post votes: %v", err)
+	}
+
+	if err := qtx.DeleteLoginFailures(context.Background(), "user:"+strings.ToLower(username)); err != nil {
+		return nil, fmt.Errorf("error deleting login failures: %v", err)
+	}
+
+	filePaths, err := qtx.DeleteUserDataExports(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting data exports: %v", err)
+	}
+
+	if err := qtx.DeleteUserById(context.Background(), userID); err != nil {
+		return nil, fmt.Errorf("error deleting user: %v", err)
+	}
+
+	return filePaths, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	ModerationActionUpdatePost      = "update_post"
+	ModerationActionDeletePost      = "delete_post"
+	ModerationActionRollbackPost    = "rollback_post"
+	ModerationActionAddPostTags     = "add_post_tags"
+	ModerationActionDeletePostTag   = "delete_post_tag"
+	ModerationActionUnsetPostAnswer = "unset_post_answer"
+	ModerationActionUpdateComment   = "update_comment"
+	ModerationActionDeleteComment   = "delete_comment"
+	ModerationActionSetUserRole     = "set_user_role"
+	ModerationActionUnlockUserLogin = "unlock_user_login"
+)
+
+// recordModerationAction keeps track of who used a privilege on what, it should run in the same tx as the action.
+func recordModerationAction(c *fiber.Ctx, qtx *repository.Queries, action string, targetID uuid.UUID) error {
+	return qtx.InsertModerationAction(context.Background(), repository.InsertModerationActionParams{
+		ActorID:  getAuthedUserID(c),
+		Action:   action,
+		TargetID: targetID,
+	})
+}
+
+// PromoteBootstrapAdmins gives the admin role to the users in the comma separated ADMIN_USER_IDS env var,
+// it runs at startup so that the first admin can exist, every other role change goes through HandleSetUserRole.
+// Removing an id from the env var doesn't demote the user.
+func PromoteBootstrapAdmins() (int64, error) {
+	var ids []uuid.UUID
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return 0, fmt.Errorf("invalid id '%s' in ADMIN_USER_IDS: %v", id, err)
+		}
+		ids = append(ids, parsed)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return queries.PromoteUsersToAdmin(context.Background(), ids)
+}
+
+func HandleUnlockUserLogin(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("user not found")
+		}
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	if err := qtx.DeleteLoginFailures(context.Background(), "user:"+strings.ToLower(repoUser.Username)); err != nil {
+		return fmt.Errorf("error deleting login failures: %v", err)
+	}
+
+	if err := recordModerationAction(c, qtx, ModerationActionUnlockUserLogin, userID); err != nil {
+		return fmt.Errorf("error recording moderation action: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+func HandleSetUserRole(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	var req SetUserRoleRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if affectedRows, err := qtx.UpdateUserRole(context.Background(), repository.UpdateUserRoleParams{
+		Role: req.Role,
+		ID:   userID,
+	}); err != nil {
+		return fmt.Errorf("error updating user role: %v", err)
+	} else if affectedRows == 0 {
+		return c.Status(fiber.StatusNotFound).SendString("user not found")
+	}
+
+	if err := recordModerationAction(c, qtx, ModerationActionSetUserRole, userID); err != nil {
+		return fmt.Errorf("error recording moderation action: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("user role updated successfully")
+}
+
+type ModerationActionsCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ModerationActionPayload struct {
+	ID        int32     `json:"id"`
+	ActorID   uuid.UUID `json:"actorID"`
+	Action    string    `json:"action"`
+	TargetID  uuid.UUID `json:"targetID"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func HandleGetModerationActions(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor ModerationActionsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	repoActions, err := queries.GetModerationActions(context.Background(), repository.GetModerationActionsParams{
+		CreatedAt: requestCursor.CreatedAt,
+		Limit:     int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting moderation actions: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoActions)
+	if hasMore {
+		responseCursor := ModerationActionsCursor{
+			CreatedAt: repoActions[limit].CreatedAt,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoActions = repoActions[:limit]
+	}
+
+	actions := make([]ModerationActionPayload, 0, len(repoActions))
+	for _, repoAction := range repoActions {
+		actions = append(actions, ModerationActionPayload{
+			ID:        repoAction.ID,
+			ActorID:   repoAction.ActorID,
+			Action:    repoAction.Action,
+			TargetID:  repoAction.TargetID,
+			CreatedAt: repoAction.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"moderationActions": actions,
+		"cursor":            encodedResponseCursor,
+		"hasMore":           hasMore,
+		"totalCount":        len(actions),
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/internals/storage"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func HandleUpdateAvatar(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("missing avatar file")
+	}
+	if fileHeader.Size > MaxAvatarUploadBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).SendString(fmt.Sprintf("avatar must be at most %d bytes", MaxAvatarUploadBytes))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("error opening avatar file: %v", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxAvatarUploadBytes))
+	if err != nil {
+		return fmt.Errorf("error reading avatar file: %v", err)
+	}
+
+	thumbnails, err := utils.ProcessAvatar(data)
+	if err != nil {
+		if errors.Is(err, utils.ErrUnsupportedImageFormat) {
+			return c.Status(fiber.StatusUnsupportedMediaType).SendString("avatar must be a png, jpeg or gif image")
+		}
+		if errors.Is(err, utils.ErrInvalidImageDimensions) {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("avatar must be between %dx%d and %dx%d pixels",
+				utils.MinAvatarDimension, utils.MinAvatarDimension, utils.MaxAvatarDimension, utils.MaxAvatarDimension))
+		}
+		return fmt.Errorf("error processing avatar: %v", err)
+	}
+
+	userID := getAuthedUserID(c)
+	// NOTE: every upload gets a new key, so that the urls change and caches don't keep serving the old avatar.
+	avatarKey := fmt.Sprintf("avatars/%s/%s", userID, uuid.New())
+	for size, thumbnail := range thumbnails {
+		if err := storage.Default.Put(context.Background(), avatarFileKey(avatarKey, size), bytes.NewReader(thumbnail), "image/png"); err != nil {
+			deleteAvatarFiles(avatarKey)
+			return fmt.Errorf("error storing avatar: %v", err)
+		}
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		deleteAvatarFiles(avatarKey)
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	oldAvatarKey, err := qtx.GetUserAvatarKey(context.Background(), userID)
+	if err != nil {
+		deleteAvatarFiles(avatarKey)
+		return fmt.Errorf("error getting avatar key: %v", err)
+	}
+
+	newAvatarKey := sql.NullString{String: avatarKey, Valid: true}
+	if err := qtx.UpdateUserAvatarKey(context.Background(), repository.UpdateUserAvatarKeyParams{
+		AvatarKey: newAvatarKey,
+		ID:        userID,
+	}); err != nil {
+		deleteAvatarFiles(avatarKey)
+		return fmt.Errorf("error updating avatar key: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		deleteAvatarFiles(avatarKey)
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	if oldAvatarKey.Valid {
+		deleteAvatarFiles(oldAvatarKey.String)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"avatarURLs": avatarURLs(userID, newAvatarKey),
+	})
+}
+
+func HandleDeleteAvatar(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	avatarKey, err := qtx.GetUserAvatarKey(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting avatar key: %v", err)
+	}
+	if !avatarKey.Valid {
+		return c.Status(fiber.StatusNotFound).SendString("no avatar is set")
+	}
+
+	if err := qtx.UpdateUserAvatarKey(context.Background(), repository.UpdateUserAvatarKeyParams{
+		AvatarKey: sql.NullString{},
+		ID:        userID,
+	}); err != nil {
+		return fmt.Errorf("error updating avatar key: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	deleteAvatarFiles(avatarKey.String)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func HandleGetIdenticon(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	size := c.QueryInt("size", utils.AvatarSizes[0])
+	if size < utils.AvatarSizes[0] || size > utils.AvatarSizes[len(utils.AvatarSizes)-1] {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid size")
+	}
+
+	identicon, err := utils.GenerateIdenticon(userID[:], size)
+	if err != nil {
+		return fmt.Errorf("error generating identicon: %v", err)
+	}
+
+	// NOTE: identicons only depend on the user id, so they never change.
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d, immutable", int((30*24*time.Hour).Seconds())))
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(identicon)
+}
+
+func avatarURLs(userID uuid.UUID, avatarKey sql.NullString) map[string]string {
+	urls := make(map[string]string, len(utils.AvatarSizes))
+	for _, size := range utils.AvatarSizes {
+		if avatarKey.Valid {
+			urls[strconv.Itoa(size)] = storage.Default.URL(avatarFileKey(avatarKey.String, size))
+		} else {
+			urls[strconv.Itoa(size)] = fmt.Sprintf("/v1/users/id/%s/identicon?size=%d", userID, size)
+		}
+	}
+	return urls
+}
+
+func avatarFileKey(avatarKey string, size int) string {
+	return fmt.Sprintf("%s/%d.png", avatarKey, size)
+}
+
+// deleteAvatarFiles removes the thumbnails of an avatar, failures only leave unused files behind so they are
+// only logged.
+func deleteAvatarFiles(avatarKey string) {
+	for _, size := range utils.AvatarSizes {
+		if err := storage.Default.Delete(context.Background(), avatarFileKey(avatarKey, size)); err != nil {
+			slog.Error("error deleting avatar file", "key", avatarFileKey(avatarKey, size), "err", err)
+		}
+	}
+}
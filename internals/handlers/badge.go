@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	BadgeTierBronze = "bronze"
+	BadgeTierSilver = "silver"
+	BadgeTierGold   = "gold"
+)
+
+// badgeCandidate is a user that meets the rule of a badge, SourceKey is empty for badges awarded once per user.
+type badgeCandidate struct {
+	UserID    uuid.UUID
+	SourceID  uuid.NullUUID
+	SourceKey string
+}
+
+type badge struct {
+	Name        string
+	Tier        string
+	Description string
+	// candidates returns the users that meet the rule and weren't awarded the badge for the same source yet.
+	candidates func(q *repository.Queries, name string) ([]badgeCandidate, error)
+}
+
+var badges = []badge{
+	{
+		Name:        "first_question",
+		Tier:        BadgeTierBronze,
+		Description: "Asked a first question",
+		candidates: func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+			rows, err := q.GetFirstPostBadgeCandidates(context.Background(), name)
+			candidates := make([]badgeCandidate, 0, len(rows))
+			for _, row := range rows {
+				candidates = append(candidates, badgeCandidate{UserID: row.UserID, SourceID: uuid.NullUUID{UUID: row.SourceID, Valid: true}})
+			}
+			return candidates, err
+		},
+	},
+	{
+		Name:        "first_accepted_answer",
+		Tier:        BadgeTierBronze,
+		Description: "Had a first answer accepted on someone else's question",
+		candidates: func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+			rows, err := q.GetFirstAcceptedAnswerBadgeCandidates(context.Background(), name)
+			candidates := make([]badgeCandidate, 0, len(rows))
+			for _, row := range rows {
+				candidates = append(candidates, badgeCandidate{UserID: row.UserID, SourceID: uuid.NullUUID{UUID: row.SourceID, Valid: true}})
+			}
+			return candidates, err
+		},
+	},
+	{
+		Name:        "nice_comment",
+		Tier:        BadgeTierBronze,
+		Description: "Comment with 10 upvotes",
+		candidates:  commentUpvotesBadgeCandidates(10),
+	},
+	{
+		Name:        "good_comment",
+		Tier:        BadgeTierSilver,
+		Description: "Comment with 25 upvotes",
+		candidates:  commentUpvotesBadgeCandidates(25),
+	},
+	{
+		Name:        "great_comment",
+		Tier:        BadgeTierGold,
+		Description: "Comment with 100 upvotes",
+		candidates:  commentUpvotesBadgeCandidates(100),
+	},
+	{
+		Name:        "tag_regular",
+		Tier:        BadgeTierSilver,
+		Description: "Asked 20 questions in a tag",
+		candidates:  tagPostsBadgeCandidates(20),
+	},
+	{
+		Name:        "tag_devotee",
+		Tier:        BadgeTierGold,
+		Description: "Asked 100 questions in a tag",
+		candidates:  tagPostsBadgeCandidates(100),
+	},
+}
+
+func commentUpvotesBadgeCandidates(minUpvotes int32) func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+	return func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+		rows, err := q.GetCommentUpvotesBadgeCandidates(context.Background(), repository.GetCommentUpvotesBadgeCandidatesParams{
+			Badge:      name,
+			MinUpvotes: minUpvotes,
+		})
+		candidates := make([]badgeCandidate, 0, len(rows))
+		for _, row := range rows {
+			candidates = append(candidates, badgeCandidate{
+				UserID:    row.UserID,
+				SourceID:  uuid.NullUUID{UUID: row.SourceID, Valid: true},
+				SourceKey: row.SourceID.String(),
+			})
+		}
+		return candidates, err
+	}
+}
+
+func tagPostsBadgeCandidates(minPosts int32) func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+	return func(q *repository.Queries, name string) ([]badgeCandidate, error) {
+		rows, err := q.GetTagPostsBadgeCandidates(context.Background(), repository.GetTagPostsBadgeCandidatesParams{
+			Badge:    name,
+			MinPosts: minPosts,
+		})
+		candidates := make([]badgeCandidate, 0, len(rows))
+		for _, row := range rows {
+			candidates = append(candidates, badgeCandidate{UserID: row.UserID, SourceKey: row.Tag})
+		}
+		return candidates, err
+	}
+}
+
+// EvaluateBadges awards every badge to the users that meet its rule and returns how many were awarded.
+// Awards are unique per user, badge and source, so running it again never duplicates them.
+func EvaluateBadges() (int, error) {
+	awardedCount := 0
+	for _, b := range badges {
+		tx, err := db.Connection.Begin()
+		if err != nil {
+			return awardedCount, fmt.Errorf("error begin tx: %v", err)
+		}
+		qtx := queries.WithTx(tx)
+
+		candidates, err := b.candidates(qtx, b.Name)
+		if err != nil {
+			tx.Rollback()
+			return awardedCount, fmt.Errorf("error getting candidates of badge %s: %v", b.Name, err)
+		}
+
+		for _, candidate := range candidates {
+			// NOTE: the content of deleted users belongs to the placeholder user, it doesn't earn badges.
+			if candidate.UserID == DeletedUserID {
+				continue
+			}
+			affectedRows, err := qtx.InsertUserBadge(context.Background(), repository.InsertUserBadgeParams{
+				UserID:    candidate.UserID,
+				Badge:     b.Name,
+				SourceID:  candidate.SourceID,
+				SourceKey: candidate.SourceKey,
+			})
+			if err != nil {
+				tx.Rollback()
+				return awardedCount, fmt.Errorf("error inserting badge %s: %v", b.Name, err)
+			}
+			awardedCount += int(affectedRows)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return awardedCount, fmt.Errorf("error commit tx: %v", err)
+		}
+	}
+	return awardedCount, nil
+}
+
+func getBadge(name string) (badge, bool) {
+	for _, b := range badges {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return badge{}, false
+}
+
+type BadgePayload struct {
+	Name        string `json:"name"`
+	Tier        string `json:"tier"`
+	Description string `json:"description"`
+}
+
+type BadgeAwardPayload struct {
+	ID        int64        `json:"id"`
+	UserID    uuid.UUID    `json:"userID"`
+	Username  string       `json:"username,omitempty"`
+	Badge     BadgePayload `json:"badge"`
+	SourceID  *uuid.UUID   `json:"sourceID,omitempty"`
+	Tag       string       `json:"tag,omitempty"`
+	AwardedAt time.Time    `json:"awardedAt"`
+}
+
+type BadgeAwardsCursor struct {
+	ID int64 `json:"id"`
+}
+
+func newBadgeAwardPayload(repoUserBadge repository.UserBadge) BadgeAwardPayload {
+	b, _ := getBadge(repoUserBadge.Badge)
+	award := BadgeAwardPayload{
+		ID:     repoUserBadge.ID,
+		UserID: repoUserBadge.UserID,
+		Badge: BadgePayload{
+			Name:        repoUserBadge.Badge,
+			Tier:        b.Tier,
+			Description: b.Description,
+		},
+		AwardedAt: repoUserBadge.AwardedAt,
+	}
+	if repoUserBadge.SourceID.Valid {
+		award.SourceID = &repoUserBadge.SourceID.UUID
+	} else {
+		award.Tag = repoUserBadge.SourceKey
+	}
+	return award
+}
+
+func HandleGetBadges(c *fiber.Ctx) error {
+	badgesPayload := make([]BadgePayload, 0, len(badges))
+	for _, b := range badges {
+		badgesPayload = append(badgesPayload, BadgePayload{
+			Name:        b.Name,
+			Tier:        b.Tier,
+			Description: b.Description,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"badges": badgesPayload,
+	})
+}
+
+func HandleGetUserBadges(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor BadgeAwardsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	if ok, err := queries.CheckUserID(context.Background(), userID); err != nil {
+		return fmt.Errorf("error checking user: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("user not found")
+	}
+
+	repoUserBadges, err := queries.GetUserBadges(context.Background(), repository.GetUserBadgesParams{
+		UserID: userID,
+		ID:     requestCursor.ID,
+		Limit:  int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting user badges: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoUserBadges)
+	if hasMore {
+		responseCursor := BadgeAwardsCursor{
+			ID: repoUserBadges[limit].ID,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoUserBadges = repoUserBadges[:limit]
+	}
+
+	awards := make([]BadgeAwardPayload, 0, len(repoUserBadges))
+	for _, repoUserBadge := range repoUserBadges {
+		awards = append(awards, newBadgeAwardPayload(repoUserBadge))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"badges":     awards,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(awards),
+	})
+}
+
+func HandleGetRecentBadgeAwards(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor BadgeAwardsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	repoAwards, err := queries.GetRecentBadgeAwards(context.Background(), repository.GetRecentBadgeAwardsParams{
+		ID:    requestCursor.ID,
+		Limit: int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting recent badge awards: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoAwards)
+	if hasMore {
+		responseCursor := BadgeAwardsCursor{
+			ID: repoAwards[limit].ID,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoAwards = repoAwards[:limit]
+	}
+
+	awards := make([]BadgeAwardPayload, 0, len(repoAwards))
+	for _, repoAward := range repoAwards {
+		award := newBadgeAwardPayload(repository.UserBadge{
+			ID:        repoAward.ID,
+			UserID:    repoAward.UserID,
+			Badge:     repoAward.Badge,
+			SourceID:  repoAward.SourceID,
+			SourceKey: repoAward.SourceKey,
+			AwardedAt: repoAward.AwardedAt,
+		})
+		award.Username = repoAward.Username
+		awards = append(awards, award)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"awards":     awards,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(awards),
+	})
+}
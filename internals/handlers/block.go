@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	UserBlockKindBlock = "block"
+	UserBlockKindMute  = "mute"
+)
+
+func HandleBlockUser(c *fiber.Ctx) error {
+	return handleSetUserBlock(c, UserBlockKindBlock)
+}
+
+func HandleUnblockUser(c *fiber.Ctx) error {
+	return handleDeleteUserBlock(c, UserBlockKindBlock)
+}
+
+func HandleMuteUser(c *fiber.Ctx) error {
+	return handleSetUserBlock(c, UserBlockKindMute)
+}
+
+func HandleUnmuteUser(c *fiber.Ctx) error {
+	return handleDeleteUserBlock(c, UserBlockKindMute)
+}
+
+// handleSetUserBlock blocks or mutes the user in the path, replacing the other kind if it was set.
+func handleSetUserBlock(c *fiber.Ctx, kind string) error {
+	blockedID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	blockerID := getAuthedUserID(c)
+	if blockedID == blockerID {
+		return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("users can't %s themselves", kind))
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if ok, err := qtx.CheckUserID(context.Background(), blockedID); err != nil {
+		return fmt.Errorf("error checking user: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("user not found")
+	}
+
+	if err := qtx.UpsertUserBlock(context.Background(), repository.UpsertUserBlockParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+		Kind:      kind,
+	}); err != nil {
+		return fmt.Errorf("error upserting user block: %v", err)
+	}
+
+	if kind == UserBlockKindBlock {
+		if err := qtx.DeleteFollowsBetween(context.Background(), repository.DeleteFollowsBetweenParams{
+			FollowerID: blockerID,
+			FolloweeID: blockedID,
+		}); err != nil {
+			return fmt.Errorf("error deleting follows: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString(fmt.Sprintf("user %sd successfully", kind))
+}
+
+func handleDeleteUserBlock(c *fiber.Ctx, kind string) error {
+	blockedID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	if affectedRows, err := queries.DeleteUserBlock(context.Background(), repository.DeleteUserBlockParams{
+		BlockerID: getAuthedUserID(c),
+		BlockedID: blockedID,
+		Kind:      kind,
+	}); err != nil {
+		return fmt.Errorf("error deleting user block: %v", err)
+	} else if affectedRows == 0 {
+		return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("user not %sd", kind))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type UserBlocksCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type UserBlockPayload struct {
+	User      UserPayload `json:"user"`
+	Kind      string      `json:"kind"`
+	BlockedAt time.Time   `json:"blockedAt"`
+}
+
+func HandleGetUserBlocks(c *fiber.Ctx) error {
+	kind := c.Query("kind", UserBlockKindBlock)
+	if !(kind == UserBlockKindBlock || kind == UserBlockKindMute) {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid kind")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor UserBlocksCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	repoBlocks, err := queries.GetUserBlocks(context.Background(), repository.GetUserBlocksParams{
+		BlockerID: getAuthedUserID(c),
+		Kind:      kind,
+		CreatedAt: requestCursor.CreatedAt,
+		Limit:     int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting user blocks: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoBlocks)
+	if hasMore {
+		responseCursor := UserBlocksCursor{
+			CreatedAt: repoBlocks[limit].BlockedAt,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoBlocks = repoBlocks[:limit]
+	}
+
+	blocks := make([]UserBlockPayload, 0, len(repoBlocks))
+	for _, repoBlock := range repoBlocks {
+		blocks = append(blocks, UserBlockPayload{
+			User:      newUserPayload(repoBlock.User),
+			Kind:      kind,
+			BlockedAt: repoBlock.BlockedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"blocks":     blocks,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(blocks),
+	})
+}
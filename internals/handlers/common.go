@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/mail"
 	"github.com/assaidy/iWonder/internals/repository"
 	"github.com/assaidy/iWonder/utils"
 	"github.com/gofiber/fiber/v2"
@@ -16,11 +21,54 @@ import (
 )
 
 const (
-	AccessTokenExpirationMinutes = 10
-	RefreshTokenExpirationDays   = 7
-	AuthedUserID                 = "middleware.auth.userID"
+	AccessTokenExpirationMinutes          = 10
+	RefreshTokenExpirationDays            = 7
+	EmailVerificationTokenExpirationHours = 24
+	PasswordResetTokenExpirationMinutes   = 30
+	TwoFactorChallengeExpirationMinutes   = 5
+	MaxTwoFactorChallengeAttempts         = 5
+	RecoveryCodesCount                    = 10
+	OidcLoginStateExpirationMinutes       = 10
+	OidcLoginCodeExpirationMinutes        = 1
+	MaxLoginFailuresPerAccount            = 5
+	MaxLoginFailuresPerIP                 = 20
+	LoginFailuresWindowHours              = 1
+	LoginLockoutBaseDuration              = 30 * time.Second
+	LoginLockoutMaxDuration               = time.Hour
+	AccountDeletionGracePeriodDays        = 14
+	DataExportExpirationDays              = 7
+	DataExportLinkExpirationMinutes       = 15
+	DataExportPendingTimeoutHours         = 1
+	MaxAvatarUploadBytes                  = 2 << 20
+	AuthedUserID                          = "middleware.auth.userID"
+	AuthedSessionID                       = "middleware.auth.sessionID"
+	AuthedScopes                          = "middleware.auth.scopes"
+	AuthedRole                            = "middleware.auth.role"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders the roles, every role has all the privileges of the ones below it.
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	ScopeVotesWrite = "votes:write"
+	// ScopeAccount is only granted to login sessions, so personal access tokens can't manage the account.
+	ScopeAccount = "account"
+)
+
+var sessionScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeVotesWrite, ScopeAccount}
+
 var (
 	queries = repository.New(db.Connection)
 )
@@ -40,6 +88,9 @@ func WithJwt(c *fiber.Ctx) error {
 	if tokenString == "" {
 		return c.Status(fiber.StatusBadRequest).SendString("missing or malformed Authorization header")
 	}
+	if strings.HasPrefix(tokenString, utils.PersonalAccessTokenPrefix) {
+		return withPersonalAccessToken(c, tokenString)
+	}
 	claims, err := utils.ParseJWTTokenString(tokenString)
 	if err != nil {
 		return c.SendStatus(fiber.StatusUnauthorized)
@@ -47,22 +98,98 @@ func WithJwt(c *fiber.Ctx) error {
 	if claims.ExpiresAt.Sub(time.Now()) < 0 {
 		return c.SendStatus(fiber.StatusUnauthorized)
 	}
-	// NOTE: if the users deleted his account, but his access token hasn't expired yet,
+	// NOTE: if the user logged out or deleted his account, but his access token hasn't expired yet,
 	// and we got a request that uses mwAuth(get's userid from context),
-	// we need to ensure that user exists.
-	if exists, err := queries.CheckUserID(context.Background(), claims.UserID); err != nil {
-		return fmt.Errorf("error checking user ID: %+v", err)
+	// we need to ensure that the session (and so the user) still exists.
+	if exists, err := queries.CheckSessionForUser(context.Background(), repository.CheckSessionForUserParams{
+		ID:     claims.SessionID,
+		UserID: claims.UserID,
+	}); err != nil {
+		return fmt.Errorf("error checking session: %+v", err)
 	} else if !exists {
 		return c.SendStatus(fiber.StatusUnauthorized)
 	}
 	c.Locals(AuthedUserID, claims.UserID)
+	c.Locals(AuthedSessionID, claims.SessionID)
+	c.Locals(AuthedScopes, sessionScopes)
+	c.Locals(AuthedRole, claims.Role)
 	return c.Next()
 }
 
+// WithOptionalJwt authenticates the request like WithJwt when it has an Authorization header,
+// and lets it through anonymously otherwise.
+func WithOptionalJwt(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderAuthorization) == "" {
+		return c.Next()
+	}
+	return WithJwt(c)
+}
+
+func withPersonalAccessToken(c *fiber.Ctx, tokenString string) error {
+	repoToken, err := queries.GetPersonalAccessTokenByHash(context.Background(), utils.HashToken(tokenString))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return fmt.Errorf("error getting personal access token: %+v", err)
+	}
+	if err := queries.UpdatePersonalAccessTokenLastUsed(context.Background(), repoToken.ID); err != nil {
+		return fmt.Errorf("error updating personal access token: %+v", err)
+	}
+	c.Locals(AuthedUserID, repoToken.UserID)
+	c.Locals(AuthedScopes, repoToken.Scopes)
+	c.Locals(AuthedRole, repoToken.Role)
+	return c.Next()
+}
+
+// RequireRole must come after WithJwt, it rejects requests from users below the role.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !hasRole(c, role) {
+			return c.Status(fiber.StatusForbidden).SendString(fmt.Sprintf("requires role '%s'", role))
+		}
+		return c.Next()
+	}
+}
+
+func hasRole(c *fiber.Ctx, role string) bool {
+	return roleRanks[c.Locals(AuthedRole).(string)] >= roleRanks[role]
+}
+
+// RequireScope must come after WithJwt, it rejects requests whose token wasn't granted the scope.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !slices.Contains(c.Locals(AuthedScopes).([]string), scope) {
+			return c.Status(fiber.StatusForbidden).SendString(fmt.Sprintf("missing scope '%s'", scope))
+		}
+		return c.Next()
+	}
+}
+
+// sendMail sends the message in the background, so that slow mail servers don't hold the request
+// and the response time doesn't reveal whether a mail was sent at all.
+func sendMail(msg mail.Message) {
+	go func() {
+		if err := mail.Default.Send(context.Background(), msg); err != nil {
+			slog.Error("error sending mail", "err", err, "to", msg.To, "subject", msg.Subject)
+		}
+	}()
+}
+
 func getAuthedUserID(c *fiber.Ctx) uuid.UUID {
 	return c.Locals(AuthedUserID).(uuid.UUID)
 }
 
+// getOptionalAuthedUserID is for routes behind WithOptionalJwt, it returns uuid.Nil for anonymous requests.
+func getOptionalAuthedUserID(c *fiber.Ctx) uuid.UUID {
+	userID, _ := c.Locals(AuthedUserID).(uuid.UUID)
+	return userID
+}
+
+func getAuthedSessionID(c *fiber.Ctx) uuid.UUID {
+	return c.Locals(AuthedSessionID).(uuid.UUID)
+}
+
 // marshalJsonAndEncodeBase64 marshals the provided source struct into JSON bytes and then encodes those bytes
 // into a base64-encoded string. Returns the base64-encoded string or an error if the marshaling fails.
 func marshalJsonAndEncodeBase64(src any) (string, error) {
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+var dataExportsDir = cmp.Or(os.Getenv("DATA_EXPORTS_DIR"), "data/exports")
+
+type DataExportPayload struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadURL,omitempty"`
+}
+
+func HandleCreateDataExport(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// NOTE: lock the user row so that concurrent requests can't both start an export.
+	if _, err := qtx.CheckUserID(context.Background(), userID); err != nil {
+		return fmt.Errorf("error checking user: %v", err)
+	}
+
+	if exists, err := qtx.CheckPendingDataExportForUser(context.Background(), repository.CheckPendingDataExportForUserParams{
+		UserID:       userID,
+		PendingSince: dataExportPendingSince(),
+	}); err != nil {
+		return fmt.Errorf("error checking pending data export: %v", err)
+	} else if exists {
+		return c.Status(fiber.StatusConflict).SendString("a data export is already in progress")
+	}
+
+	exportID := uuid.New()
+	if err := qtx.InsertDataExport(context.Background(), repository.InsertDataExportParams{
+		ID:     exportID,
+		UserID: userID,
+	}); err != nil {
+		return fmt.Errorf("error inserting data export: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	go func() {
+		if err := buildDataExport(exportID, userID); err != nil {
+			slog.Error("error building data export", "exportID", exportID, "err", err)
+			if err := queries.SetDataExportAsFailed(context.Background(), repository.SetDataExportAsFailedParams{
+				ExpiresAt: sql.NullTime{Time: time.Now().Add(DataExportExpirationDays * 24 * time.Hour), Valid: true},
+				ID:        exportID,
+			}); err != nil {
+				slog.Error("error setting data export as failed", "exportID", exportID, "err", err)
+			}
+		}
+	}()
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"export": DataExportPayload{
+			ID:        exportID,
+			Status:    DataExportStatusPending,
+			CreatedAt: time.Now(),
+		},
+	})
+}
+
+func HandleGetDataExport(c *fiber.Ctx) error {
+	exportID, err := uuid.Parse(c.Params("export_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid export id")
+	}
+
+	userID := getAuthedUserID(c)
+	repoExport, err := queries.GetDataExportForUser(context.Background(), repository.GetDataExportForUserParams{
+		ID:     exportID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("export not found")
+		}
+		return fmt.Errorf("error getting data export: %v", err)
+	}
+
+	export := DataExportPayload{
+		ID:        repoExport.ID,
+		Status:    repoExport.Status,
+		CreatedAt: repoExport.CreatedAt,
+	}
+	// NOTE: an export pending for that long was interrupted by a restart, it's never going to be ready.
+	if repoExport.Status == DataExportStatusPending && repoExport.CreatedAt.Before(dataExportPendingSince()) {
+		export.Status = DataExportStatusFailed
+	}
+	if repoExport.Status == DataExportStatusReady {
+		export.ExpiresAt = &repoExport.ExpiresAt.Time
+		token, err := utils.GenerateJWTActionToken(utils.ActionClaims{
+			UserID:  userID,
+			Purpose: utils.ActionDownloadDataExport,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        exportID.String(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(DataExportLinkExpirationMinutes * time.Minute)),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error generating download token: %v", err)
+		}
+		export.DownloadURL = fmt.Sprintf("%s/v1/users/exports/%s/download?token=%s", c.BaseURL(), exportID, token)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"export": export,
+	})
+}
+
+func HandleDownloadDataExport(c *fiber.Ctx) error {
+	exportID, err := uuid.Parse(c.Params("export_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid export id")
+	}
+
+	claims, err := utils.ParseJWTActionToken(c.Query("token"), utils.ActionDownloadDataExport)
+	if err != nil || claims.ID != exportID.String() {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired download link")
+	}
+
+	repoExport, err := queries.GetDataExportForUser(context.Background(), repository.GetDataExportForUserParams{
+		ID:     exportID,
+		UserID: claims.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("export not found")
+		}
+		return fmt.Errorf("error getting data export: %v", err)
+	}
+	if repoExport.Status != DataExportStatusReady || time.Now().After(repoExport.ExpiresAt.Time) {
+		return c.Status(fiber.StatusNotFound).SendString("export not found")
+	}
+
+	return c.Download(repoExport.FilePath.String, fmt.Sprintf("iwonder-export-%s.zip", repoExport.CreatedAt.Format("2006-01-02")))
+}
+
+// DeleteExpiredDataExports deletes the exports that can't be downloaded anymore along with their files,
+// and the ones that were interrupted before they got ready.
+func DeleteExpiredDataExports() error {
+	filePaths, err := queries.DeleteExpiredDataExports(context.Background(), dataExportPendingSince())
+	if err != nil {
+		return fmt.Errorf("error deleting expired data exports: %v", err)
+	}
+	removeDataExportFiles(filePaths)
+	return nil
+}
+
+// dataExportPendingSince is the creation time before which a pending export is considered interrupted.
+func dataExportPendingSince() time.Time {
+	return time.Now().Add(-DataExportPendingTimeoutHours * time.Hour)
+}
+
+func removeDataExportFiles(filePaths []sql.NullString) {
+	for _, filePath := range filePaths {
+		if !filePath.Valid {
+			continue
+		}
+		if err := os.Remove(filePath.String); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Error("error removing data export file", "path", filePath.String, "err", err)
+		}
+	}
+}
+
+type exportedProfile struct {
+	UserPayload
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	Role            string     `json:"role"`
+}
+
+type exportedPost struct {
+	PostPayload
+	Tags []string `json:"tags"`
+}
+
+type exportedVote struct {
+	CommentID uuid.UUID `json:"commentID"`
+	Kind      string    `json:"kind"`
+}
+
+type exportedPostVote struct {
+	PostID uuid.UUID `json:"postID"`
+	Kind   string    `json:"kind"`
+}
+
+type exportedTag struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type exportedAcceptedAnswer struct {
+	PostID    uuid.UUID `json:"postID"`
+	CommentID uuid.UUID `json:"commentID"`
+	// AcceptedByUser tells whether the user accepted this answer on their post, or it's their comment that was accepted.
+	AcceptedByUser bool `json:"acceptedByUser"`
+}
+
+// buildDataExport writes the archive of everything the user contributed and marks the export as ready.
+func buildDataExport(exportID, userID uuid.UUID) error {
+	if err := os.MkdirAll(dataExportsDir, 0o700); err != nil {
+		return fmt.Errorf("error creating data exports dir: %v", err)
+	}
+
+	filePath := filepath.Join(dataExportsDir, exportID.String()+".zip")
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating archive file: %v", err)
+	}
+	defer file.Close()
+
+	if err := writeDataExportArchive(file, userID); err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("error closing archive file: %v", err)
+	}
+
+	if err := queries.SetDataExportAsReady(context.Background(), repository.SetDataExportAsReadyParams{
+		FilePath:  sql.NullString{String: filePath, Valid: true},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(DataExportExpirationDays * 24 * time.Hour), Valid: true},
+		ID:        exportID,
+	}); err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("error setting data export as ready: %v", err)
+	}
+
+	return nil
+}
+
+func writeDataExportArchive(w io.Writer, userID uuid.UUID) error {
+	// NOTE: read everything in one repeatable read tx so that the files agree with each other.
+	tx, err := db.Connection.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	zw := zip.NewWriter(w)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+	profile := exportedProfile{
+		UserPayload: newUserPayload(repoUser),
+		Email:       repoUser.Email.String,
+		Role:        repoUser.Role,
+	}
+	if repoUser.EmailVerifiedAt.Valid {
+		profile.EmailVerifiedAt = &repoUser.EmailVerifiedAt.Time
+	}
+	if err := writeZipJson(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	repoPosts, err := qtx.GetAllUserPosts(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting posts: %v", err)
+	}
+	posts := make([]exportedPost, 0, len(repoPosts))
+	for _, repoPost := range repoPosts {
+		tags, err := qtx.GetPostTags(context.Background(), repoPost.ID)
+		if err != nil {
+			return fmt.Errorf("error getting post tags: %v", err)
+		}
+		post := exportedPost{
+			PostPayload: newPostPayload(repoPost),
+			Tags:        tags,
+		}
+		posts = append(posts, post)
+
+		markdown := fmt.Sprintf("# %s\n\nPosted at: %s\n", post.Title, post.CreatedAt.UTC().Format(time.RFC3339))
+		if len(post.Tags) > 0 {
+			markdown += fmt.Sprintf("Tags: %s\n", strings.Join(post.Tags, ", "))
+		}
+		markdown += "\n" + post.Content + "\n"
+		if err := writeZipFile(zw, fmt.Sprintf("posts/%s.md", post.ID), []byte(markdown)); err != nil {
+			return err
+		}
+	}
+	if err := writeZipJson(zw, "posts.json", posts); err != nil {
+		return err
+	}
+
+	repoComments, err := qtx.GetAllUserComments(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting comments: %v", err)
+	}
+	comments := make([]CommentPayload, 0, len(repoComments))
+	for _, repoComment := range repoComments {
+		comment := newCommentPayload(repoComment)
+		comments = append(comments, comment)
+
+		markdown := fmt.Sprintf("Comment on post %s\n\nPosted at: %s\n\n%s\n",
+			comment.PostID, comment.CreatedAt.UTC().Format(time.RFC3339), comment.Content)
+		if err := writeZipFile(zw, fmt.Sprintf("comments/%s.md", comment.ID), []byte(markdown)); err != nil {
+			return err
+		}
+	}
+	if err := writeZipJson(zw, "comments.json", comments); err != nil {
+		return err
+	}
+
+	repoVotes, err := qtx.GetAllUserCommentVotes(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting comment votes: %v", err)
+	}
+	votes := make([]exportedVote, 0, len(repoVotes))
+	for _, repoVote := range repoVotes {
+		votes = append(votes, exportedVote{
+			CommentID: repoVote.CommentID,
+			Kind:      repoVote.Kind,
+		})
+	}
+	if err := writeZipJson(zw, "votes.json", votes); err != nil {
+		return err
+	}
+
+	repoPostVotes, err := qtx.GetAllUserPostVotes(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting post votes: %v", err)
+	}
+	postVotes := make([]exportedPostVote, 0, len(repoPostVotes))
+	for _, repoPostVote := range repoPostVotes {
+		postVotes = append(postVotes, exportedPostVote{
+			PostID: repoPostVote.PostID,
+			Kind:   repoPostVote.Kind,
+		})
+	}
+	if err := writeZipJson(zw, "post_votes.json", postVotes); err != nil {
+		return err
+	}
+
+	repoTags, err := qtx.GetTagsCreatedByUser(context.Background(), uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("error getting tags: %v", err)
+	}
+	tags := make([]exportedTag, 0, len(repoTags))
+	for _, repoTag := range repoTags {
+		tags = append(tags, exportedTag{
+			Name:      repoTag.Name,
+			CreatedAt: repoTag.CreatedAt,
+		})
+	}
+	if err := writeZipJson(zw, "tags.json", tags); err != nil {
+		return err
+	}
+
+	repoAnswers, err := qtx.GetUserAcceptedAnswers(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting accepted answers: %v", err)
+	}
+	answers := make([]exportedAcceptedAnswer, 0, len(repoAnswers))
+	for _, repoAnswer := range repoAnswers {
+		answers = append(answers, exportedAcceptedAnswer{
+			PostID:         repoAnswer.PostID,
+			CommentID:      repoAnswer.CommentID,
+			AcceptedByUser: repoAnswer.AcceptedByUser,
+		})
+	}
+	if err := writeZipJson(zw, "accepted_answers.json", answers); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+
+	return nil
+}
+
+func writeZipJson(zw *zip.Writer, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling %s: %v", name, err)
+	}
+	return writeZipFile(zw, name, data)
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating %s in archive: %v", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("error writing %s to archive: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func HandleFollowUser(c *fiber.Ctx) error {
+	followeeID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	followerID := getAuthedUserID(c)
+	if followeeID == followerID {
+		return c.Status(fiber.StatusBadRequest).SendString("users can't follow themselves")
+	}
+
+	if ok, err := queries.CheckUserID(context.Background(), followeeID); err != nil {
+		return fmt.Errorf("error checking user: %v", err)
+	} else if !ok || followeeID == DeletedUserID {
+		return c.Status(fiber.StatusNotFound).SendString("user not found")
+	}
+
+	if blocked, err := queries.CheckUsersBlocked(context.Background(), repository.CheckUsersBlockedParams{
+		BlockerID: followerID,
+		BlockedID: followeeID,
+	}); err != nil {
+		return fmt.Errorf("error checking block: %v", err)
+	} else if blocked {
+		return c.Status(fiber.StatusForbidden).SendString("you can't follow a user you blocked or who blocked you")
+	}
+
+	if _, err := queries.InsertFollow(context.Background(), repository.InsertFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}); err != nil {
+		return fmt.Errorf("error inserting follow: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("user followed successfully")
+}
+
+func HandleUnfollowUser(c *fiber.Ctx) error {
+	followeeID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	if affectedRows, err := queries.DeleteFollow(context.Background(), repository.DeleteFollowParams{
+		FollowerID: getAuthedUserID(c),
+		FolloweeID: followeeID,
+	}); err != nil {
+		return fmt.Errorf("error deleting follow: %v", err)
+	} else if affectedRows == 0 {
+		return c.Status(fiber.StatusNotFound).SendString("user not followed")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type FollowsCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type FollowPayload struct {
+	User       UserPayload `json:"user"`
+	FollowedAt time.Time   `json:"followedAt"`
+}
+
+func HandleGetUserFollowers(c *fiber.Ctx) error {
+	return handleGetFollows(c, func(userID uuid.UUID, cursor time.Time, limit int32) ([]FollowPayload, error) {
+		repoFollows, err := queries.GetUserFollowers(context.Background(), repository.GetUserFollowersParams{
+			FolloweeID: userID,
+			CreatedAt:  cursor,
+			Limit:      limit,
+		})
+		follows := make([]FollowPayload, 0, len(repoFollows))
+		for _, repoFollow := range repoFollows {
+			follows = append(follows, FollowPayload{
+				User:       newUserPayload(repoFollow.User),
+				FollowedAt: repoFollow.FollowedAt,
+			})
+		}
+		return follows, err
+	})
+}
+
+func HandleGetUserFollowing(c *fiber.Ctx) error {
+	return handleGetFollows(c, func(userID uuid.UUID, cursor time.Time, limit int32) ([]FollowPayload, error) {
+		repoFollows, err := queries.GetUserFollowing(context.Background(), repository.GetUserFollowingParams{
+			FollowerID: userID,
+			CreatedAt:  cursor,
+			Limit:      limit,
+		})
+		follows := make([]FollowPayload, 0, len(repoFollows))
+		for _, repoFollow := range repoFollows {
+			follows = append(follows, FollowPayload{
+				User:       newUserPayload(repoFollow.User),
+				FollowedAt: repoFollow.FollowedAt,
+			})
+		}
+		return follows, err
+	})
+}
+
+// handleGetFollows paginates the followers or followees of the user in the path, which getFollows lists.
+func handleGetFollows(c *fiber.Ctx, getFollows func(userID uuid.UUID, cursor time.Time, limit int32) ([]FollowPayload, error)) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor FollowsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	if ok, err := queries.CheckUserID(context.Background(), userID); err != nil {
+		return fmt.Errorf("error checking user: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("user not found")
+	}
+
+	follows, err := getFollows(userID, requestCursor.CreatedAt, int32(limit)+1)
+	if err != nil {
+		return fmt.Errorf("error getting follows: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(follows)
+	if hasMore {
+		responseCursor := FollowsCursor{
+			CreatedAt: follows[limit].FollowedAt,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		follows = follows[:limit]
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"follows":    follows,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(follows),
+	})
+}
+
+type FeedCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type FeedItemPayload struct {
+	// Kind is 'post' for new questions and 'answer' for comments, PostID and Title are of the answered post.
+	Kind        string    `json:"kind"`
+	ID          uuid.UUID `json:"id"`
+	PostID      uuid.UUID `json:"postID"`
+	UserID      uuid.UUID `json:"userID"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	ContentHtml string    `json:"contentHtml"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func HandleGetFollowingFeed(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor FeedCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	repoItems, err := queries.GetFollowingFeed(context.Background(), repository.GetFollowingFeedParams{
+		FollowerID: getAuthedUserID(c),
+		CreatedAt:  requestCursor.CreatedAt,
+		Limit:      int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting following feed: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoItems)
+	if hasMore {
+		responseCursor := FeedCursor{
+			CreatedAt: repoItems[limit].CreatedAt,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoItems = repoItems[:limit]
+	}
+
+	items := make([]FeedItemPayload, 0, len(repoItems))
+	for _, repoItem := range repoItems {
+		items = append(items, FeedItemPayload{
+			Kind:        repoItem.Kind,
+			ID:          repoItem.ID,
+			PostID:      repoItem.PostID,
+			UserID:      repoItem.UserID,
+			Title:       repoItem.Title,
+			Content:     repoItem.Content,
+			ContentHtml: repoItem.ContentHtml,
+			CreatedAt:   repoItem.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"items":      items,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(items),
+	})
+}
@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func HandleGetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.Status(fiber.StatusOK).JSON(utils.GetJWKSet())
+}
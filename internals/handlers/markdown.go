@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+)
+
+// RerenderStalePosts re-renders the cached html of up to limit posts rendered by another version of the
+// markdown renderer and returns how many were re-rendered.
+func RerenderStalePosts(limit int32) (int, error) {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoPosts, err := qtx.GetStalePostsContent(context.Background(), repository.GetStalePostsContentParams{
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
+		Limit:              limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting stale posts: %v", err)
+	}
+
+	for _, repoPost := range repoPosts {
+		contentHtml, err := utils.RenderMarkdown(repoPost.Content)
+		if err != nil {
+			return 0, fmt.Errorf("error rendering post %s: %v", repoPost.ID, err)
+		}
+		if err := qtx.UpdatePostContentHtml(context.Background(), repository.UpdatePostContentHtmlParams{
+			ID:                 repoPost.ID,
+			ContentHtml:        contentHtml,
+			ContentHtmlVersion: utils.MarkdownRendererVersion,
+		}); err != nil {
+			return 0, fmt.Errorf("error updating post %s: %v", repoPost.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return len(repoPosts), nil
+}
+
+// RerenderStaleComments is RerenderStalePosts for comments.
+func RerenderStaleComments(limit int32) (int, error) {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoComments, err := qtx.GetStaleCommentsContent(context.Background(), repository.GetStaleCommentsContentParams{
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
+		Limit:              limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting stale comments: %v", err)
+	}
+
+	for _, repoComment := range repoComments {
+		contentHtml, err := utils.RenderMarkdown(repoComment.Content)
+		if err != nil {
+			return 0, fmt.Errorf("error rendering comment %s: %v", repoComment.ID, err)
+		}
+		if err := qtx.UpdateCommentContentHtml(context.Background(), repository.UpdateCommentContentHtmlParams{
+			ID:                 repoComment.ID,
+			ContentHtml:        contentHtml,
+			ContentHtmlVersion: utils.MarkdownRendererVersion,
+		}); err != nil {
+			return 0, fmt.Errorf("error updating comment %s: %v", repoComment.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return len(repoComments), nil
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/oidc"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var nonUsernameCharsRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// oidcStateCookie ties a login to the browser that started it. Without it, anyone could start a login, stop at
+// the provider's redirect, and have a victim's browser finish it into their account.
+const oidcStateCookie = "oidc_state"
+
+func HandleOidcLogin(c *fiber.Ctx) error {
+	provider, ok := oidc.Providers[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("provider not found")
+	}
+
+	state := oidc.GenerateRandomString()
+	nonce := oidc.GenerateRandomString()
+	codeVerifier := oidc.GenerateRandomString()
+
+	if err := queries.InsertOidcLoginState(context.Background(), repository.InsertOidcLoginStateParams{
+		State:        state,
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(OidcLoginStateExpirationMinutes * time.Minute),
+	}); err != nil {
+		return fmt.Errorf("error inserting oidc login state: %v", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, codeVerifier)
+	if err != nil {
+		return fmt.Errorf("error building authorization url: %v", err)
+	}
+
+	// NOTE: Lax still sends the cookie on the top level redirect back from the provider.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/v1/users/oidc/" + provider.Name,
+		MaxAge:   OidcLoginStateExpirationMinutes * 60,
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// HandleOidcCallback finishes the login with the provider, then redirects back to the app with a short lived,
+// single use code that the app exchanges for the tokens at HandleOidcToken. The tokens themselves never go
+// through the browser's redirects.
+func HandleOidcCallback(c *fiber.Ctx) error {
+	provider, ok := oidc.Providers[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("provider not found")
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		return c.Status(fiber.StatusUnauthorized).SendString("login with provider failed: " + errCode)
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("missing state or code query parameter")
+	}
+
+	cookieState := c.Cookies(oidcStateCookie)
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/v1/users/oidc/" + provider.Name,
+		Expires:  time.Unix(0, 0),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	if subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired state")
+	}
+
+	repoState, err := queries.DeleteOidcLoginState(context.Background(), repository.DeleteOidcLoginStateParams{
+		State:    state,
+		Provider: provider.Name,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired state")
+		}
+		return fmt.Errorf("error deleting oidc login state: %v", err)
+	}
+
+	claims, err := provider.Exchange(context.Background(), code, repoState.CodeVerifier, repoState.Nonce)
+	if err != nil {
+		slog.Warn("error verifying oidc identity", "err", err, "provider", provider.Name)
+		return c.Status(fiber.StatusUnauthorized).SendString("error verifying identity with provider")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	var userID uuid.UUID
+	repoIdentity, err := qtx.GetUserIdentity(context.Background(), repository.GetUserIdentityParams{
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		userID = repoIdentity.UserID
+	} else if errors.Is(err, sql.ErrNoRows) {
+		userID, err = createOidcUser(qtx, provider.Name, claims)
+		if err != nil {
+			return fmt.Errorf("error creating user from oidc identity: %v", err)
+		}
+	} else {
+		return fmt.Errorf("error getting user identity: %v", err)
+	}
+
+	loginCode := oidc.GenerateRandomString()
+	if err := qtx.InsertOidcLoginCode(context.Background(), repository.InsertOidcLoginCodeParams{
+		CodeHash:  utils.HashToken(loginCode),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(OidcLoginCodeExpirationMinutes * time.Minute),
+	}); err != nil {
+		return fmt.Errorf("error inserting oidc login code: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	// NOTE: the code goes in the fragment, which browsers don't send to the app's server or in the referer.
+	return c.Redirect(fmt.Sprintf("%s/oidc/callback#code=%s", os.Getenv("APP_URL"), loginCode), fiber.StatusFound)
+}
+
+type OidcTokenRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// HandleOidcToken exchanges the code HandleOidcCallback handed to the app for a session, or for a two factor
+// challenge when the user enabled it, just like a login with a password.
+func HandleOidcToken(c *fiber.Ctx) error {
+	var req OidcTokenRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	userID, err := qtx.DeleteOidcLoginCode(context.Background(), utils.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired code")
+		}
+		return fmt.Errorf("error deleting oidc login code: %v", err)
+	}
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	if repoUser.TotpEnabledAt.Valid {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error commit tx: %v", err)
+		}
+		challengeToken, err := createTwoFactorChallenge(repoUser.ID)
+		if err != nil {
+			return fmt.Errorf("error creating two factor challenge: %v", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"twoFactorRequired": true,
+			"challengeToken":    challengeToken,
+		})
+	}
+
+	accessToken, refreshToken, err := createSession(c, qtx, repoUser)
+	if err != nil {
+		return fmt.Errorf("error creating session: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":         newUserPayload(repoUser),
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+	})
+}
+
+// createOidcUser creates the account of someone logging in with a provider for the first time,
+// and links the provider identity to it.
+func createOidcUser(qtx *repository.Queries, providerName string, claims *oidc.IDTokenClaims) (uuid.UUID, error) {
+	baseUsername := claims.PreferredUsername
+	if baseUsername == "" {
+		baseUsername, _, _ = strings.Cut(claims.Email, "@")
+	}
+	baseUsername = nonUsernameCharsRegex.ReplaceAllString(baseUsername, "_")
+	baseUsername = strings.Trim(baseUsername, "_")
+	if baseUsername == "" {
+		baseUsername = "user"
+	}
+	baseUsername = baseUsername[:min(len(baseUsername), 40)]
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = baseUsername
+	}
+	name = string([]rune(name)[:min(len([]rune(name)), 100)])
+
+	userID := uuid.New()
+	created := false
+	for attempt := range 10 {
+		username := baseUsername
+		if attempt > 0 {
+			username = fmt.Sprintf("%s_%04d", baseUsername, rand.IntN(10000))
+		}
+		// NOTE: accounts created through a provider have no password, and an empty hash never matches one.
+		affectedRows, err := qtx.InsertUser(context.Background(), repository.InsertUserParams{
+			ID:             userID,
+			Name:           name,
+			Username:       username,
+			HashedPassword: "",
+		})
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if affectedRows > 0 {
+			created = true
+			break
+		}
+	}
+	if !created {
+		return uuid.Nil, fmt.Errorf("couldn't generate a free username from '%s'", baseUsername)
+	}
+
+	email := sql.NullString{String: strings.ToLower(claims.Email), Valid: claims.Email != ""}
+	if email.Valid && claims.EmailVerified {
+		if taken, err := qtx.CheckVerifiedEmailExceptUserID(context.Background(), repository.CheckVerifiedEmailExceptUserIDParams{
+			Email: email,
+			ID:    userID,
+		}); err != nil {
+			return uuid.Nil, err
+		} else if !taken {
+			if err := qtx.UpdateUserEmail(context.Background(), repository.UpdateUserEmailParams{
+				Email: email,
+				ID:    userID,
+			}); err != nil {
+				return uuid.Nil, err
+			}
+			if _, err := qtx.SetUserEmailAsVerified(context.Background(), repository.SetUserEmailAsVerifiedParams{
+				ID:    userID,
+				Email: email,
+			}); err != nil {
+				return uuid.Nil, err
+			}
+		}
+	}
+
+	if err := qtx.InsertUserIdentity(context.Background(), repository.InsertUserIdentityParams{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		UserID:   userID,
+		Email:    email,
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// DeleteExpiredOidcLoginStates deletes the states of logins that were abandoned before returning from the provider,
+// and the login codes the app never exchanged.
+func DeleteExpiredOidcLoginStates() error {
+	if err := queries.DeleteExpiredOidcLoginStates(context.Background()); err != nil {
+		return fmt.Errorf("error deleting expired oidc login states: %v", err)
+	}
+	if err := queries.DeleteExpiredOidcLoginCodes(context.Background()); err != nil {
+		return fmt.Errorf("error deleting expired oidc login codes: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PersonalAccessTokenPayload struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name   string   `json:"name" validate:"required,customNoOuterSpaces,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,unique,dive,oneof=posts:read posts:write votes:write"`
+	// ExpiresInDays of 0 means the token never expires.
+	ExpiresInDays int `json:"expiresInDays" validate:"min=0,max=365"`
+}
+
+func HandleCreatePersonalAccessToken(c *fiber.Ctx) error {
+	var req CreatePersonalAccessTokenRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	userID := getAuthedUserID(c)
+
+	var expiresAt sql.NullTime
+	if req.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().Add(time.Hour * 24 * time.Duration(req.ExpiresInDays)), Valid: true}
+	}
+
+	token := utils.GeneratePersonalAccessToken()
+	repoToken, err := queries.InsertPersonalAccessToken(context.Background(), repository.InsertPersonalAccessTokenParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("error inserting personal access token: %v", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"personalAccessToken": newPersonalAccessTokenPayload(repoToken),
+		"token":               token,
+	})
+}
+
+func HandleGetPersonalAccessTokens(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	repoTokens, err := queries.GetUserPersonalAccessTokens(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting personal access tokens: %v", err)
+	}
+
+	tokens := make([]PersonalAccessTokenPayload, 0, len(repoTokens))
+	for _, repoToken := range repoTokens {
+		tokens = append(tokens, newPersonalAccessTokenPayload(repoToken))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"personalAccessTokens": tokens,
+	})
+}
+
+func HandleDeletePersonalAccessToken(c *fiber.Ctx) error {
+	tokenID, err := uuid.Parse(c.Params("token_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid token id")
+	}
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if ok, err := qtx.CheckPersonalAccessTokenForUser(context.Background(), repository.CheckPersonalAccessTokenForUserParams{
+		ID:     tokenID,
+		UserID: userID,
+	}); err != nil {
+		return fmt.Errorf("error checking personal access token: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("token not found for user")
+	}
+
+	if err := qtx.DeletePersonalAccessToken(context.Background(), tokenID); err != nil {
+		return fmt.Errorf("error deleting personal access token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func newPersonalAccessTokenPayload(repoToken repository.PersonalAccessToken) PersonalAccessTokenPayload {
+	payload := PersonalAccessTokenPayload{
+		ID:        repoToken.ID,
+		Name:      repoToken.Name,
+		Scopes:    repoToken.Scopes,
+		CreatedAt: repoToken.CreatedAt,
+	}
+	if repoToken.ExpiresAt.Valid {
+		payload.ExpiresAt = &repoToken.ExpiresAt.Time
+	}
+	if repoToken.LastUsedAt.Valid {
+		payload.LastUsedAt = &repoToken.LastUsedAt.Time
+	}
+	return payload
+}
@@ -5,22 +5,124 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/mail"
 	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type PostPayload struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"userID"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	Answered  bool      `json:"answered"`
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"userID"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	ContentHtml string    `json:"contentHtml"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Answered    bool      `json:"answered"`
+	UpCount     int32     `json:"upCount"`
+	DownCount   int32     `json:"downCount"`
+	Score       int32     `json:"score"`
+	// ViewerVote is the kind of the authed user's vote on the post, it's empty if they didn't vote.
+	ViewerVote string `json:"viewerVote,omitempty"`
+}
+
+func newPostPayload(repoPost repository.Post) PostPayload {
+	return PostPayload{
+		ID:          repoPost.ID,
+		UserID:      repoPost.UserID,
+		Title:       repoPost.Title,
+		Content:     repoPost.Content,
+		ContentHtml: repoPost.ContentHtml,
+		CreatedAt:   repoPost.CreatedAt,
+		Answered:    repoPost.Answered,
+		UpCount:     repoPost.UpCount,
+		DownCount:   repoPost.DownCount,
+		Score:       repoPost.Score,
+	}
+}
+
+// setPostsViewerVote fills the ViewerVote of the posts for routes behind WithOptionalJwt, with one query for all of them.
+func setPostsViewerVote(c *fiber.Ctx, posts []PostPayload) error {
+	viewerID := getOptionalAuthedUserID(c)
+	if viewerID == uuid.Nil || len(posts) == 0 {
+		return nil
+	}
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	repoVotes, err := queries.GetPostVotesForUser(context.Background(), repository.GetPostVotesForUserParams{
+		UserID:  viewerID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		return err
+	}
+	votes := make(map[uuid.UUID]string, len(repoVotes))
+	for _, repoVote := range repoVotes {
+		votes[repoVote.PostID] = repoVote.Kind
+	}
+	for i := range posts {
+		posts[i].ViewerVote = votes[posts[i].ID]
+	}
+	return nil
+}
+
+// contentAccess is how the authed user is allowed to modify some content.
+type contentAccess int
+
+const (
+	accessNone contentAccess = iota
+	accessOwner
+	accessModerator
+	// accessEditor is for users that unlocked PrivilegeEditPosts on posts they don't own.
+	accessEditor
+)
+
+func getPostAccess(c *fiber.Ctx, qtx *repository.Queries, postID uuid.UUID) (contentAccess, error) {
+	if ok, err := qtx.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
+		ID:     postID,
+		UserID: getAuthedUserID(c),
+	}); err != nil {
+		return accessNone, err
+	} else if ok {
+		return accessOwner, nil
+	}
+	if !hasRole(c, RoleModerator) {
+		return accessNone, nil
+	}
+	if ok, err := qtx.CheckPost(context.Background(), postID); err != nil {
+		return accessNone, err
+	} else if ok {
+		return accessModerator, nil
+	}
+	return accessNone, nil
+}
+
+func getCommentAccess(c *fiber.Ctx, qtx *repository.Queries, commentID uuid.UUID) (contentAccess, error) {
+	if ok, err := qtx.CheckCommentForUser(context.Background(), repository.CheckCommentForUserParams{
+		ID:     commentID,
+		UserID: getAuthedUserID(c),
+	}); err != nil {
+		return accessNone, err
+	} else if ok {
+		return accessOwner, nil
+	}
+	if !hasRole(c, RoleModerator) {
+		return accessNone, nil
+	}
+	if ok, err := qtx.CheckComment(context.Background(), commentID); err != nil {
+		return accessNone, err
+	} else if ok {
+		return accessModerator, nil
+	}
+	return accessNone, nil
 }
 
 type CreatePostRequest struct {
@@ -36,25 +138,45 @@ func HandleCreatePost(c *fiber.Ctx) error {
 
 	userID := getAuthedUserID(c)
 
-	repoPost, err := queries.InsertPost(context.Background(), repository.InsertPostParams{
-		ID:      uuid.New(),
-		UserID:  userID,
-		Title:   req.Title,
-		Content: req.Content,
+	contentHtml, err := utils.RenderMarkdown(req.Content)
+	if err != nil {
+		return fmt.Errorf("error rendering content: %v", err)
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoPost, err := qtx.InsertPost(context.Background(), repository.InsertPostParams{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		Title:              req.Title,
+		Content:            req.Content,
+		ContentHtml:        contentHtml,
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
 	})
 	if err != nil {
 		return fmt.Errorf("error inserting post: %v", err)
 	}
 
+	if _, err := qtx.InsertPostRevision(context.Background(), repository.InsertPostRevisionParams{
+		PostID:  repoPost.ID,
+		UserID:  userID,
+		Title:   repoPost.Title,
+		Content: repoPost.Content,
+	}); err != nil {
+		return fmt.Errorf("error inserting post revision: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"post": PostPayload{
-			ID:        repoPost.ID,
-			UserID:    repoPost.UserID,
-			Title:     repoPost.Title,
-			Content:   repoPost.Content,
-			CreatedAt: repoPost.CreatedAt,
-			Answered:  repoPost.Answered,
-		},
+		"post": newPostPayload(repoPost),
 	})
 }
 
@@ -72,21 +194,20 @@ func HandleGetPost(c *fiber.Ctx) error {
 		return fmt.Errorf("error getting post: %v", err)
 	}
 
+	posts := []PostPayload{newPostPayload(repoPost)}
+	if err := setPostsViewerVote(c, posts); err != nil {
+		return fmt.Errorf("error getting post votes: %v", err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"post": PostPayload{
-			ID:        repoPost.ID,
-			UserID:    repoPost.UserID,
-			Title:     repoPost.Title,
-			Content:   repoPost.Content,
-			CreatedAt: repoPost.CreatedAt,
-			Answered:  repoPost.Answered,
-		},
+		"post": posts[0],
 	})
 }
 
 type UpdatePostRequest struct {
-	Title   string `json:"title" validate:"required,customNoOuterSpaces,max=200"`
-	Content string `json:"content" validate:"required,customNoOuterSpaces"`
+	Title       string `json:"title" validate:"required,customNoOuterSpaces,max=200"`
+	Content     string `json:"content" validate:"required,customNoOuterSpaces"`
+	EditSummary string `json:"editSummary" validate:"customNoOuterSpaces,max=300"`
 }
 
 func HandleUpdatePost(c *fiber.Ctx) error {
@@ -99,7 +220,11 @@ func HandleUpdatePost(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
 	}
-	userID := getAuthedUserID(c)
+
+	contentHtml, err := utils.RenderMarkdown(req.Content)
+	if err != nil {
+		return fmt.Errorf("error rendering content: %v", err)
+	}
 
 	tx, err := db.Connection.Begin()
 	if err != nil {
@@ -108,23 +233,51 @@ func HandleUpdatePost(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
-		ID:     postID,
-		UserID: userID,
-	}); err != nil {
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
 		return fmt.Errorf("error checking post: %v", err)
-	} else if !ok {
-		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
+	} else if access == accessNone {
+		if ok, err := qtx.CheckPost(context.Background(), postID); err != nil {
+			return fmt.Errorf("error checking post: %v", err)
+		} else if !ok {
+			return c.Status(fiber.StatusNotFound).SendString("post not found")
+		}
+		if ok, err := hasPrivilege(c, qtx, PrivilegeEditPosts); err != nil {
+			return fmt.Errorf("error checking privilege: %v", err)
+		} else if !ok {
+			return sendMissingPrivilege(c, PrivilegeEditPosts)
+		}
+		access = accessEditor
 	}
 
 	if err := qtx.UpdatePostByID(context.Background(), repository.UpdatePostByIDParams{
-		ID:      postID,
-		Title:   req.Title,
-		Content: req.Content,
+		ID:                 postID,
+		Title:              req.Title,
+		Content:            req.Content,
+		ContentHtml:        contentHtml,
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
 	}); err != nil {
 		return fmt.Errorf("error updating post: %v", err)
 	}
 
+	// NOTE: the post row is locked by the update, so concurrent edits can't take the same revision number.
+	if _, err := qtx.InsertPostRevision(context.Background(), repository.InsertPostRevisionParams{
+		PostID:      postID,
+		UserID:      getAuthedUserID(c),
+		Title:       req.Title,
+		Content:     req.Content,
+		EditSummary: sql.NullString{String: req.EditSummary, Valid: req.EditSummary != ""},
+	}); err != nil {
+		return fmt.Errorf("error inserting post revision: %v", err)
+	}
+
+	// NOTE: edits by editors aren't moderation, they are only kept in the revisions like the owner's.
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionUpdatePost, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
@@ -137,21 +290,44 @@ func HandleDeletePost(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
 	}
-	userID := getAuthedUserID(c)
 
-	if ok, err := queries.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
-		ID:     postID,
-		UserID: userID,
-	}); err != nil {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
 		return fmt.Errorf("error checking post: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
 	}
 
-	if err := queries.DeletePostByID(context.Background(), postID); err != nil {
+	// NOTE: the post's comments go with it, and so does the reputation their votes and acceptance gave.
+	commentIDs, err := qtx.GetPostCommentIDs(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("error getting post comment ids: %v", err)
+	}
+	if err := revokeSourcesReputation(qtx, commentIDs); err != nil {
+		return fmt.Errorf("error revoking reputation: %v", err)
+	}
+
+	if err := qtx.DeletePostByID(context.Background(), postID); err != nil {
 		return fmt.Errorf("error deleting post: %v", err)
 	}
 
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionDeletePost, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -164,7 +340,6 @@ func HandleAddPostTags(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
 	}
-	userID := getAuthedUserID(c)
 
 	tx, err := db.Connection.Begin()
 	if err != nil {
@@ -173,12 +348,10 @@ func HandleAddPostTags(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
-		ID:     postID,
-		UserID: userID,
-	}); err != nil {
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
 		return fmt.Errorf("error checking post: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
 	}
 
@@ -187,8 +360,27 @@ func HandleAddPostTags(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	canCreateTags := false
 	for _, tag := range req.Tags {
-		tagID, err := qtx.InsertTag(context.Background(), strings.ToLower(strings.TrimSpace(tag)))
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if !canCreateTags {
+			if exists, err := qtx.CheckTag(context.Background(), tag); err != nil {
+				return fmt.Errorf("error checking tag: %v", err)
+			} else if !exists {
+				if ok, err := hasPrivilege(c, qtx, PrivilegeCreateTags); err != nil {
+					return fmt.Errorf("error checking privilege: %v", err)
+				} else if !ok {
+					return sendMissingPrivilege(c, PrivilegeCreateTags)
+				}
+				canCreateTags = true
+			}
+		}
+
+		tagID, err := qtx.InsertTag(context.Background(), repository.InsertTagParams{
+			Name:      tag,
+			CreatedBy: uuid.NullUUID{UUID: getAuthedUserID(c), Valid: true},
+		})
 		if err != nil {
 			return fmt.Errorf("error inserting tag: %v", err)
 		}
@@ -201,6 +393,12 @@ func HandleAddPostTags(c *fiber.Ctx) error {
 		}
 	}
 
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionAddPostTags, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("err commit tx: %v", err)
 	}
@@ -238,7 +436,6 @@ func HandleDeletePostTag(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
 	}
-	userID := getAuthedUserID(c)
 
 	tx, err := db.Connection.Begin()
 	if err != nil {
@@ -247,12 +444,10 @@ func HandleDeletePostTag(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
-		ID:     postID,
-		UserID: userID,
-	}); err != nil {
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
 		return fmt.Errorf("error checking post: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
 	}
 
@@ -265,6 +460,12 @@ func HandleDeletePostTag(c *fiber.Ctx) error {
 		return fmt.Errorf("error deleting post tag: %v", err)
 	}
 
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionDeletePostTag, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("err commit tx: %v", err)
 	}
@@ -296,20 +497,45 @@ func HandleCreateComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).SendString("post not found")
 	}
 
+	if blocked, err := qtx.CheckPostBlockedForUser(context.Background(), repository.CheckPostBlockedForUserParams{
+		ID:        postID,
+		BlockedID: userID,
+	}); err != nil {
+		return fmt.Errorf("error checking block: %v", err)
+	} else if blocked {
+		return c.Status(fiber.StatusForbidden).SendString("the post owner blocked you")
+	}
+
 	var req CreateCommentRequest
 	if err := parseAndValidateJsonBody(c, &req); err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	contentHtml, err := utils.RenderMarkdown(req.Content)
+	if err != nil {
+		return fmt.Errorf("error rendering content: %v", err)
+	}
+
+	commentID := uuid.New()
 	if err := qtx.InsertComment(context.Background(), repository.InsertCommentParams{
-		ID:      uuid.New(),
-		PostID:  postID,
-		UserID:  userID,
-		Content: req.Content,
+		ID:                 commentID,
+		PostID:             postID,
+		UserID:             userID,
+		Content:            req.Content,
+		ContentHtml:        contentHtml,
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
 	}); err != nil {
 		return fmt.Errorf("error inserting comment: %v", err)
 	}
 
+	if err := qtx.InsertCommentRevision(context.Background(), repository.InsertCommentRevisionParams{
+		CommentID: commentID,
+		UserID:    userID,
+		Content:   req.Content,
+	}); err != nil {
+		return fmt.Errorf("error inserting comment revision: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
@@ -326,7 +552,6 @@ func HandleUpdateComment(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid comment id")
 	}
-	userID := getAuthedUserID(c)
 
 	tx, err := db.Connection.Begin()
 	if err != nil {
@@ -335,12 +560,10 @@ func HandleUpdateComment(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckCommentForUser(context.Background(), repository.CheckCommentForUserParams{
-		ID:     commentID,
-		UserID: userID,
-	}); err != nil {
+	access, err := getCommentAccess(c, qtx, commentID)
+	if err != nil {
 		return fmt.Errorf("error checking comment for user: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("comment not found for user")
 	}
 
@@ -349,17 +572,56 @@ func HandleUpdateComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	contentHtml, err := utils.RenderMarkdown(req.Content)
+	if err != nil {
+		return fmt.Errorf("error rendering content: %v", err)
+	}
+
 	if err := qtx.UpdateComment(context.Background(), repository.UpdateCommentParams{
-		ID:      commentID,
-		Content: req.Content,
+		ID:                 commentID,
+		Content:            req.Content,
+		ContentHtml:        contentHtml,
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
 	}); err != nil {
 		return fmt.Errorf("error udpating comment: %v", err)
 	}
 
+	// NOTE: the comment row is locked by the access check, so concurrent edits can't take the same revision number.
+	if err := qtx.InsertCommentRevision(context.Background(), repository.InsertCommentRevisionParams{
+		CommentID: commentID,
+		UserID:    getAuthedUserID(c),
+		Content:   req.Content,
+	}); err != nil {
+		return fmt.Errorf("error inserting comment revision: %v", err)
+	}
+
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionUpdateComment, commentID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
+	repoAnswerPostOwner, err := qtx.GetAnswerPostOwner(context.Background(), commentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting answer post owner: %v", err)
+	}
+	isAnswer := err == nil
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
 
+	// NOTE: the question author accepted the answer as it was, so let them know it changed.
+	if isAnswer && repoAnswerPostOwner.UserID != getAuthedUserID(c) && repoAnswerPostOwner.EmailVerifiedAt.Valid {
+		sendMail(mail.Message{
+			To:      repoAnswerPostOwner.Email.String,
+			Subject: "The accepted answer to your question was edited",
+			Body: fmt.Sprintf("The answer you accepted on your question '%s' was edited.\n\n"+
+				"You can review the changes in the revisions of the answer:\n%s/posts/%s",
+				repoAnswerPostOwner.Title, os.Getenv("APP_URL"), repoAnswerPostOwner.PostID),
+		})
+	}
+
 	return c.Status(fiber.StatusOK).SendString("comment updated successfully")
 }
 
@@ -368,34 +630,85 @@ func HandleDeleteComment(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid comment id")
 	}
-	userID := getAuthedUserID(c)
 
-	if ok, err := queries.CheckCommentForUser(context.Background(), repository.CheckCommentForUserParams{
-		ID:     commentID,
-		UserID: userID,
-	}); err != nil {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	access, err := getCommentAccess(c, qtx, commentID)
+	if err != nil {
 		return fmt.Errorf("error checking comment for user: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("comment not found for user")
 	}
 
-	if err := queries.DeleteComment(context.Background(), commentID); err != nil {
+	// NOTE: the reputation the comment's votes and acceptance gave doesn't outlive it.
+	if err := revokeSourcesReputation(qtx, []uuid.UUID{commentID}); err != nil {
+		return fmt.Errorf("error revoking reputation: %v", err)
+	}
+
+	if err := qtx.DeleteComment(context.Background(), commentID); err != nil {
 		return fmt.Errorf("error deleting comment: %v", err)
 	}
 
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionDeleteComment, commentID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+const (
+	CommentsSortNewest = "newest"
+	CommentsSortScore  = "score"
+)
+
 type CommentsCursor struct {
 	CreatedAt time.Time `json:"createdAt"`
+	// Score is only used when sorting by score.
+	Score int32 `json:"score"`
 }
 
 type CommentPayload struct {
-	ID        uuid.UUID `json:"id"`
-	PostID    uuid.UUID `json:"postID"`
-	UserID    uuid.UUID `json:"userID"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID          uuid.UUID  `json:"id"`
+	PostID      uuid.UUID  `json:"postID"`
+	UserID      uuid.UUID  `json:"userID"`
+	Content     string     `json:"content"`
+	ContentHtml string     `json:"contentHtml"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Edited      bool       `json:"edited"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	UpCount     int32      `json:"upCount"`
+	DownCount   int32      `json:"downCount"`
+	Score       int32      `json:"score"`
+}
+
+func newCommentPayload(repoComment repository.Comment) CommentPayload {
+	comment := CommentPayload{
+		ID:          repoComment.ID,
+		PostID:      repoComment.PostID,
+		UserID:      repoComment.UserID,
+		Content:     repoComment.Content,
+		ContentHtml: repoComment.ContentHtml,
+		CreatedAt:   repoComment.CreatedAt,
+		Edited:      repoComment.EditedAt.Valid,
+		UpCount:     repoComment.UpCount,
+		DownCount:   repoComment.DownCount,
+		Score:       repoComment.Score,
+	}
+	if repoComment.EditedAt.Valid {
+		comment.EditedAt = &repoComment.EditedAt.Time
+	}
+	return comment
 }
 
 func HandleGetAllPostComments(c *fiber.Ctx) error {
@@ -415,16 +728,33 @@ func HandleGetAllPostComments(c *fiber.Ctx) error {
 		limit = 10
 	}
 
+	sort := c.Query("sort", CommentsSortNewest)
+	if !(sort == CommentsSortNewest || sort == CommentsSortScore) {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid sort")
+	}
+
 	var requestCursor CommentsCursor
 	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
 	}
 
-	repoComments, err := queries.GetPostComments(context.Background(), repository.GetPostCommentsParams{
-		PostID:    postID,
-		CreatedAt: requestCursor.CreatedAt,
-		Limit:     int32(limit),
-	})
+	var repoComments []repository.Comment
+	if sort == CommentsSortScore {
+		repoComments, err = queries.GetPostCommentsByScore(context.Background(), repository.GetPostCommentsByScoreParams{
+			PostID:    postID,
+			Score:     requestCursor.Score,
+			CreatedAt: requestCursor.CreatedAt,
+			ViewerID:  getOptionalAuthedUserID(c),
+			Limit:     int32(limit) + 1,
+		})
+	} else {
+		repoComments, err = queries.GetPostComments(context.Background(), repository.GetPostCommentsParams{
+			PostID:    postID,
+			CreatedAt: requestCursor.CreatedAt,
+			ViewerID:  getOptionalAuthedUserID(c),
+			Limit:     int32(limit) + 1,
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("error getting post comments: %v", err)
 	}
@@ -434,6 +764,7 @@ func HandleGetAllPostComments(c *fiber.Ctx) error {
 	if hasMore {
 		responseCursor := CommentsCursor{
 			CreatedAt: repoComments[limit].CreatedAt,
+			Score:     repoComments[limit].Score,
 		}
 		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
 		if err != nil {
@@ -444,13 +775,7 @@ func HandleGetAllPostComments(c *fiber.Ctx) error {
 
 	comments := make([]CommentPayload, 0, len(repoComments))
 	for _, repoComment := range repoComments {
-		comments = append(comments, CommentPayload{
-			ID:        repoComment.ID,
-			PostID:    repoComment.PostID,
-			UserID:    repoComment.UserID,
-			Content:   repoComment.Content,
-			CreatedAt: repoComment.CreatedAt,
-		})
+		comments = append(comments, newCommentPayload(repoComment))
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -485,8 +810,43 @@ func HandleVoteComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).SendString("comment not found")
 	}
 
+	if kind == "down" {
+		if ok, err := hasPrivilege(c, qtx, PrivilegeDownvote); err != nil {
+			return fmt.Errorf("error checking privilege: %v", err)
+		} else if !ok {
+			return sendMissingPrivilege(c, PrivilegeDownvote)
+		}
+	}
+
 	userID := getAuthedUserID(c)
 
+	if blocked, err := qtx.CheckCommentBlockedForUser(context.Background(), repository.CheckCommentBlockedForUserParams{
+		ID:        commentID,
+		BlockedID: userID,
+	}); err != nil {
+		return fmt.Errorf("error checking block: %v", err)
+	} else if blocked {
+		return c.Status(fiber.StatusForbidden).SendString("the comment or post owner blocked you")
+	}
+
+	authorID, err := qtx.GetCommentAuthor(context.Background(), commentID)
+	if err != nil {
+		return fmt.Errorf("error getting comment author: %v", err)
+	}
+
+	repoVote, err := qtx.GetCommentVote(context.Background(), repository.GetCommentVoteParams{
+		CommentID: commentID,
+		UserID:    userID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting comment vote: %v", err)
+	}
+	voteExists := err == nil
+
+	if voteExists && repoVote.Kind == kind {
+		return c.Status(fiber.StatusOK).SendString("vote made successfully")
+	}
+
 	if err := qtx.InsertCommentVote(context.Background(), repository.InsertCommentVoteParams{
 		UserID:    userID,
 		CommentID: commentID,
@@ -495,6 +855,28 @@ func HandleVoteComment(c *fiber.Ctx) error {
 		return fmt.Errorf("error inserting comment vote: %v", err)
 	}
 
+	upDelta, downDelta := voteCountsDelta(kind)
+	if voteExists {
+		oldUpDelta, oldDownDelta := voteCountsDelta(repoVote.Kind)
+		upDelta, downDelta = upDelta-oldUpDelta, downDelta-oldDownDelta
+	}
+	if err := qtx.AddCommentVoteCounts(context.Background(), repository.AddCommentVoteCountsParams{
+		UpDelta:   upDelta,
+		DownDelta: downDelta,
+		ID:        commentID,
+	}); err != nil {
+		return fmt.Errorf("error updating comment vote counts: %v", err)
+	}
+
+	if voteExists {
+		if err := revokeVoteReputation(qtx, commentID, userID, repoVote.Kind); err != nil {
+			return fmt.Errorf("error revoking vote reputation: %v", err)
+		}
+	}
+	if err := awardVoteReputation(qtx, commentID, authorID, userID, kind); err != nil {
+		return fmt.Errorf("error awarding vote reputation: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
@@ -510,22 +892,48 @@ func HandleUnvoteComment(c *fiber.Ctx) error {
 
 	userID := getAuthedUserID(c)
 
-	if ok, err := queries.CheckCommentVoteForUser(context.Background(), repository.CheckCommentVoteForUserParams{
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoVote, err := qtx.GetCommentVote(context.Background(), repository.GetCommentVoteParams{
 		CommentID: commentID,
 		UserID:    userID,
-	}); err != nil {
-		return fmt.Errorf("error checking comment vote: %v", err)
-	} else if !ok {
-		return c.Status(fiber.StatusNotFound).SendString("comment vote not found for user")
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("comment vote not found for user")
+		}
+		return fmt.Errorf("error getting comment vote: %v", err)
 	}
 
-	if err := queries.DeleteCommentVote(context.Background(), repository.DeleteCommentVoteParams{
+	if err := qtx.DeleteCommentVote(context.Background(), repository.DeleteCommentVoteParams{
 		CommentID: commentID,
 		UserID:    userID,
 	}); err != nil {
 		return fmt.Errorf("error deleting comment vote: %v", err)
 	}
 
+	upDelta, downDelta := voteCountsDelta(repoVote.Kind)
+	if err := qtx.AddCommentVoteCounts(context.Background(), repository.AddCommentVoteCountsParams{
+		UpDelta:   -upDelta,
+		DownDelta: -downDelta,
+		ID:        commentID,
+	}); err != nil {
+		return fmt.Errorf("error updating comment vote counts: %v", err)
+	}
+
+	if err := revokeVoteReputation(qtx, commentID, userID, repoVote.Kind); err != nil {
+		return fmt.Errorf("error revoking vote reputation: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -535,6 +943,40 @@ func HandleGetCommentVoteCounts(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid comment id")
 	}
 
+	voteCounts, err := queries.GetCommentVoteCounts(context.Background(), commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("comment not found")
+		}
+		return fmt.Errorf("error getting comment vote counts: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"upCount":   voteCounts.UpCount,
+		"downCount": voteCounts.DownCount,
+		"score":     voteCounts.Score,
+	})
+}
+
+// voteCountsDelta is how the up and down counts change when a vote of kind is added, negate it for removed votes.
+func voteCountsDelta(kind string) (int32, int32) {
+	if kind == "up" {
+		return 1, 0
+	}
+	return 0, 1
+}
+
+func HandleVotePost(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	kind := c.Query("kind")
+	if !(kind == "up" || kind == "down") {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid vote kind")
+	}
+
 	tx, err := db.Connection.Begin()
 	if err != nil {
 		return fmt.Errorf("error bigin tx: %v", err)
@@ -542,26 +984,151 @@ func HandleGetCommentVoteCounts(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckComment(context.Background(), commentID); err != nil {
-		return fmt.Errorf("error checking comment: %v", err)
+	if ok, err := qtx.CheckPost(context.Background(), postID); err != nil {
+		return fmt.Errorf("error checking post: %v", err)
 	} else if !ok {
-		return c.Status(fiber.StatusNotFound).SendString("comment not found")
+		return c.Status(fiber.StatusNotFound).SendString("post not found")
+	}
+
+	userID := getAuthedUserID(c)
+
+	if authorID, err := qtx.GetPostAuthor(context.Background(), postID); err != nil {
+		return fmt.Errorf("error getting post author: %v", err)
+	} else if authorID == userID {
+		return c.Status(fiber.StatusForbidden).SendString("users can't vote on their own posts")
+	}
+
+	if kind == "down" {
+		if ok, err := hasPrivilege(c, qtx, PrivilegeDownvote); err != nil {
+			return fmt.Errorf("error checking privilege: %v", err)
+		} else if !ok {
+			return sendMissingPrivilege(c, PrivilegeDownvote)
+		}
+	}
+
+	if blocked, err := qtx.CheckPostBlockedForUser(context.Background(), repository.CheckPostBlockedForUserParams{
+		ID:        postID,
+		BlockedID: userID,
+	}); err != nil {
+		return fmt.Errorf("error checking block: %v", err)
+	} else if blocked {
+		return c.Status(fiber.StatusForbidden).SendString("the post owner blocked you")
+	}
+
+	repoVote, err := qtx.GetPostVote(context.Background(), repository.GetPostVoteParams{
+		PostID: postID,
+		UserID: userID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting post vote: %v", err)
+	}
+	voteExists := err == nil
+
+	if voteExists && repoVote.Kind == kind {
+		return c.Status(fiber.StatusOK).SendString("vote made successfully")
+	}
+
+	if err := qtx.InsertPostVote(context.Background(), repository.InsertPostVoteParams{
+		PostID: postID,
+		UserID: userID,
+		Kind:   kind,
+	}); err != nil {
+		return fmt.Errorf("error inserting post vote: %v", err)
+	}
+
+	upDelta, downDelta := voteCountsDelta(kind)
+	if voteExists {
+		oldUpDelta, oldDownDelta := voteCountsDelta(repoVote.Kind)
+		upDelta, downDelta = upDelta-oldUpDelta, downDelta-oldDownDelta
+	}
+	if err := qtx.AddPostVoteCounts(context.Background(), repository.AddPostVoteCountsParams{
+		UpDelta:   upDelta,
+		DownDelta: downDelta,
+		ID:        postID,
+	}); err != nil {
+		return fmt.Errorf("error updating post vote counts: %v", err)
 	}
 
-	voteCounts, err := qtx.GetCommentVoteCounts(context.Background(), commentID)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("vote made successfully")
+}
+
+func HandleUnvotePost(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
 	if err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("error getting comment vote counts: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoVote, err := qtx.GetPostVote(context.Background(), repository.GetPostVoteParams{
+		PostID: postID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("post vote not found for user")
 		}
+		return fmt.Errorf("error getting post vote: %v", err)
+	}
+
+	if err := qtx.DeletePostVote(context.Background(), repository.DeletePostVoteParams{
+		PostID: postID,
+		UserID: userID,
+	}); err != nil {
+		return fmt.Errorf("error deleting post vote: %v", err)
+	}
+
+	upDelta, downDelta := voteCountsDelta(repoVote.Kind)
+	if err := qtx.AddPostVoteCounts(context.Background(), repository.AddPostVoteCountsParams{
+		UpDelta:   -upDelta,
+		DownDelta: -downDelta,
+		ID:        postID,
+	}); err != nil {
+		return fmt.Errorf("error updating post vote counts: %v", err)
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
 
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func HandleGetPostVoteCounts(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	repoPost, err := queries.GetPostByID(context.Background(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("post not found")
+		}
+		return fmt.Errorf("error getting post: %v", err)
+	}
+
+	posts := []PostPayload{newPostPayload(repoPost)}
+	if err := setPostsViewerVote(c, posts); err != nil {
+		return fmt.Errorf("error getting post votes: %v", err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"upCount":   voteCounts.UpCount,
-		"downCount": voteCounts.DownCount,
+		"upCount":    posts[0].UpCount,
+		"downCount":  posts[0].DownCount,
+		"score":      posts[0].Score,
+		"viewerVote": posts[0].ViewerVote,
 	})
 }
 
@@ -599,6 +1166,16 @@ func HandleSetPostAnswer(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).SendString("comment not found")
 	}
 
+	repoAnswer, err := qtx.GetPostAnswer(context.Background(), postID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting post answer: %v", err)
+	}
+	answerExists := err == nil
+
+	if answerExists && repoAnswer.ID == commentID {
+		return c.Status(fiber.StatusOK).SendString("post answerd successfully")
+	}
+
 	if err := qtx.InsertPostAnswer(context.Background(), repository.InsertPostAnswerParams{
 		PostID:    postID,
 		CommentID: commentID,
@@ -606,6 +1183,19 @@ func HandleSetPostAnswer(c *fiber.Ctx) error {
 		return fmt.Errorf("error setting post answer: %v", err)
 	}
 
+	if answerExists {
+		if err := revokeAnswerReputation(qtx, repoAnswer.ID, userID); err != nil {
+			return fmt.Errorf("error revoking answer reputation: %v", err)
+		}
+	}
+	authorID, err := qtx.GetCommentAuthor(context.Background(), commentID)
+	if err != nil {
+		return fmt.Errorf("error getting comment author: %v", err)
+	}
+	if err := awardAnswerReputation(qtx, commentID, authorID, userID); err != nil {
+		return fmt.Errorf("error awarding answer reputation: %v", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error commit tx: %v", err)
 	}
@@ -618,21 +1208,51 @@ func HandleUnsetPostAnswer(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
 	}
-	userID := getAuthedUserID(c)
 
-	if ok, err := queries.CheckPostForUser(context.Background(), repository.CheckPostForUserParams{
-		ID:     postID,
-		UserID: userID,
-	}); err != nil {
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
 		return fmt.Errorf("error checking post: %v", err)
-	} else if !ok {
+	} else if access == accessNone {
 		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
 	}
 
-	if err := queries.DeletePostAnswer(context.Background(), postID); err != nil {
+	repoAnswer, err := qtx.GetPostAnswer(context.Background(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		return fmt.Errorf("error getting post answer: %v", err)
+	}
+
+	if err := qtx.DeletePostAnswer(context.Background(), postID); err != nil {
 		return fmt.Errorf("error deleting post answer: %v", err)
 	}
 
+	repoPost, err := qtx.GetPostByID(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("error getting post: %v", err)
+	}
+	if err := revokeAnswerReputation(qtx, repoAnswer.ID, repoPost.UserID); err != nil {
+		return fmt.Errorf("error revoking answer reputation: %v", err)
+	}
+
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionUnsetPostAnswer, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -655,7 +1275,7 @@ func HandleGetPostAnswer(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).SendString("post not found")
 	}
 
-	repoComment, err := qtx.GetPostAnswer(context.Background(), postID)
+	repoAnswer, err := qtx.GetPostAnswer(context.Background(), postID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(fiber.StatusNoContent).SendString("no answer for this post")
@@ -668,13 +1288,20 @@ func HandleGetPostAnswer(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"comment": CommentPayload{
-			ID:        repoComment.ID,
-			PostID:    repoComment.PostID,
-			UserID:    repoComment.UserID,
-			Content:   repoComment.Content,
-			CreatedAt: repoComment.CreatedAt,
-		},
+		"comment": newCommentPayload(repository.Comment{
+			ID:          repoAnswer.ID,
+			PostID:      repoAnswer.PostID,
+			UserID:      repoAnswer.UserID,
+			Content:     repoAnswer.Content,
+			ContentHtml: repoAnswer.ContentHtml,
+			CreatedAt:   repoAnswer.CreatedAt,
+			EditedAt:    repoAnswer.EditedAt,
+			UpCount:     repoAnswer.UpCount,
+			DownCount:   repoAnswer.DownCount,
+			Score:       repoAnswer.Score,
+		}),
+		"acceptedAt":          repoAnswer.AcceptedAt,
+		"editedSinceAccepted": repoAnswer.EditedAt.Valid && repoAnswer.EditedAt.Time.After(repoAnswer.AcceptedAt),
 	})
 }
 
@@ -728,14 +1355,10 @@ func HandleGetAllPostsForUser(c *fiber.Ctx) error {
 
 	posts := make([]PostPayload, 0, len(repoPosts))
 	for _, repoPost := range repoPosts {
-		posts = append(posts, PostPayload{
-			ID:        repoPost.ID,
-			UserID:    repoPost.UserID,
-			Title:     repoPost.Title,
-			Content:   repoPost.Content,
-			CreatedAt: repoPost.CreatedAt,
-			Answered:  repoPost.Answered,
-		})
+		posts = append(posts, newPostPayload(repoPost))
+	}
+	if err := setPostsViewerVote(c, posts); err != nil {
+		return fmt.Errorf("error getting post votes: %v", err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -767,6 +1390,7 @@ func HandleGetAllPosts(c *fiber.Ctx) error {
 		CreatedAt: requestCursor.CreatedAt,
 		Query:     query,
 		Tags:      tags,
+		ViewerID:  getOptionalAuthedUserID(c),
 		Limit:     int32(limit),
 	})
 	if err != nil {
@@ -788,14 +1412,10 @@ func HandleGetAllPosts(c *fiber.Ctx) error {
 
 	posts := make([]PostPayload, 0, len(repoPosts))
 	for _, repoPost := range repoPosts {
-		posts = append(posts, PostPayload{
-			ID:        repoPost.ID,
-			UserID:    repoPost.UserID,
-			Title:     repoPost.Title,
-			Content:   repoPost.Content,
-			CreatedAt: repoPost.CreatedAt,
-			Answered:  repoPost.Answered,
-		})
+		posts = append(posts, newPostPayload(repoPost))
+	}
+	if err := setPostsViewerVote(c, posts); err != nil {
+		return fmt.Errorf("error getting post votes: %v", err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
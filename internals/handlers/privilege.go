@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	PrivilegeDownvote   = "downvote"
+	PrivilegeCreateTags = "create_tags"
+	PrivilegeEditPosts  = "edit_posts"
+)
+
+// PrivilegeRequirements are the activity a user needs before a privilege is unlocked, all of them must be met.
+type PrivilegeRequirements struct {
+	AcceptedAnswers int32 `json:"acceptedAnswers"`
+	NetUpvotes      int32 `json:"netUpvotes"`
+	AccountAgeDays  int32 `json:"accountAgeDays"`
+}
+
+// privileges are ordered from the easiest to unlock, every requirement can be changed with the
+// PRIVILEGE_<NAME>_ACCEPTED_ANSWERS, PRIVILEGE_<NAME>_NET_UPVOTES and PRIVILEGE_<NAME>_ACCOUNT_AGE_DAYS env vars,
+// like PRIVILEGE_DOWNVOTE_NET_UPVOTES.
+var privileges = []struct {
+	Name         string
+	Requirements PrivilegeRequirements
+}{
+	{PrivilegeDownvote, privilegeRequirementsFromEnv("DOWNVOTE", PrivilegeRequirements{
+		AcceptedAnswers: 0,
+		NetUpvotes:      5,
+		AccountAgeDays:  1,
+	})},
+	{PrivilegeCreateTags, privilegeRequirementsFromEnv("CREATE_TAGS", PrivilegeRequirements{
+		AcceptedAnswers: 1,
+		NetUpvotes:      10,
+		AccountAgeDays:  7,
+	})},
+	{PrivilegeEditPosts, privilegeRequirementsFromEnv("EDIT_POSTS", PrivilegeRequirements{
+		AcceptedAnswers: 5,
+		NetUpvotes:      50,
+		AccountAgeDays:  30,
+	})},
+}
+
+func privilegeRequirementsFromEnv(name string, fallback PrivilegeRequirements) PrivilegeRequirements {
+	return PrivilegeRequirements{
+		AcceptedAnswers: envInt32("PRIVILEGE_"+name+"_ACCEPTED_ANSWERS", fallback.AcceptedAnswers),
+		NetUpvotes:      envInt32("PRIVILEGE_"+name+"_NET_UPVOTES", fallback.NetUpvotes),
+		AccountAgeDays:  envInt32("PRIVILEGE_"+name+"_ACCOUNT_AGE_DAYS", fallback.AccountAgeDays),
+	}
+}
+
+func getPrivilegeRequirements(privilege string) PrivilegeRequirements {
+	for _, p := range privileges {
+		if p.Name == privilege {
+			return p.Requirements
+		}
+	}
+	panic("unknown privilege: " + privilege)
+}
+
+// UserActivityStats is what the privilege requirements are checked against.
+type UserActivityStats struct {
+	AcceptedAnswers int32 `json:"acceptedAnswers"`
+	NetUpvotes      int32 `json:"netUpvotes"`
+	AccountAgeDays  int32 `json:"accountAgeDays"`
+}
+
+func getUserActivityStats(q *repository.Queries, userID uuid.UUID) (UserActivityStats, error) {
+	repoStats, err := q.GetUserActivityStats(context.Background(), userID)
+	if err != nil {
+		return UserActivityStats{}, err
+	}
+	return UserActivityStats{
+		AcceptedAnswers: repoStats.AcceptedAnswers,
+		NetUpvotes:      repoStats.NetUpvotes,
+		AccountAgeDays:  int32(time.Since(repoStats.CreatedAt).Hours() / 24),
+	}, nil
+}
+
+func (s UserActivityStats) meets(r PrivilegeRequirements) bool {
+	return s.AcceptedAnswers >= r.AcceptedAnswers && s.NetUpvotes >= r.NetUpvotes && s.AccountAgeDays >= r.AccountAgeDays
+}
+
+// hasPrivilege tells whether the authed user unlocked the privilege, moderators have all of them.
+func hasPrivilege(c *fiber.Ctx, q *repository.Queries, privilege string) (bool, error) {
+	if hasRole(c, RoleModerator) {
+		return true, nil
+	}
+	stats, err := getUserActivityStats(q, getAuthedUserID(c))
+	if err != nil {
+		return false, err
+	}
+	return stats.meets(getPrivilegeRequirements(privilege)), nil
+}
+
+func sendMissingPrivilege(c *fiber.Ctx, privilege string) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":        "missing privilege",
+		"privilege":    privilege,
+		"requirements": getPrivilegeRequirements(privilege),
+	})
+}
+
+type PrivilegePayload struct {
+	Name         string                `json:"name"`
+	Unlocked     bool                  `json:"unlocked"`
+	Requirements PrivilegeRequirements `json:"requirements"`
+}
+
+func HandleGetUserPrivileges(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	repoUser, err := queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("user not found")
+		}
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	stats, err := getUserActivityStats(queries, userID)
+	if err != nil {
+		return fmt.Errorf("error getting user activity stats: %v", err)
+	}
+
+	isModerator := roleRanks[repoUser.Role] >= roleRanks[RoleModerator]
+	userPrivileges := make([]PrivilegePayload, 0, len(privileges))
+	for _, p := range privileges {
+		userPrivileges = append(userPrivileges, PrivilegePayload{
+			Name:         p.Name,
+			Unlocked:     isModerator || stats.meets(p.Requirements),
+			Requirements: p.Requirements,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"stats":      stats,
+		"privileges": userPrivileges,
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/google/uuid"
+)
+
+// ReconcileVoteCounts recomputes the vote counts of every post and comment from their votes, batchSize rows per tx,
+// and returns how many posts and comments had drifted counts.
+func ReconcileVoteCounts(batchSize int32) (int64, int64, error) {
+	fixedPostsCount, err := reconcileInBatches(batchSize, func(qtx *repository.Queries, afterID uuid.UUID) ([]uuid.UUID, int64, error) {
+		ids, err := qtx.LockPostsBatch(context.Background(), repository.LockPostsBatchParams{
+			ID:    afterID,
+			Limit: batchSize,
+		})
+		if err != nil || len(ids) == 0 {
+			return ids, 0, err
+		}
+		fixedCount, err := qtx.ReconcilePostVoteCounts(context.Background(), ids)
+		return ids, fixedCount, err
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reconciling post vote counts: %v", err)
+	}
+
+	fixedCommentsCount, err := reconcileInBatches(batchSize, func(qtx *repository.Queries, afterID uuid.UUID) ([]uuid.UUID, int64, error) {
+		ids, err := qtx.LockCommentsBatch(context.Background(), repository.LockCommentsBatchParams{
+			ID:    afterID,
+			Limit: batchSize,
+		})
+		if err != nil || len(ids) == 0 {
+			return ids, 0, err
+		}
+		fixedCount, err := qtx.ReconcileCommentVoteCounts(context.Background(), ids)
+		return ids, fixedCount, err
+	})
+	if err != nil {
+		return fixedPostsCount, 0, fmt.Errorf("error reconciling comment vote counts: %v", err)
+	}
+
+	return fixedPostsCount, fixedCommentsCount, nil
+}
+
+// reconcileInBatches walks the rows ordered by id, reconcile must lock the batch of rows after afterID before
+// counting their votes. The vote handlers lock the voted row too, so votes cast meanwhile are never lost.
+func reconcileInBatches(batchSize int32, reconcile func(qtx *repository.Queries, afterID uuid.UUID) ([]uuid.UUID, int64, error)) (int64, error) {
+	var totalFixedCount int64
+	afterID := uuid.Nil
+	for {
+		tx, err := db.Connection.Begin()
+		if err != nil {
+			return totalFixedCount, fmt.Errorf("error begin tx: %v", err)
+		}
+
+		ids, fixedCount, err := reconcile(queries.WithTx(tx), afterID)
+		if err != nil {
+			tx.Rollback()
+			return totalFixedCount, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return totalFixedCount, fmt.Errorf("error commit tx: %v", err)
+		}
+
+		totalFixedCount += fixedCount
+		if len(ids) < int(batchSize) {
+			return totalFixedCount, nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
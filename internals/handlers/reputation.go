@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	ReputationCommentUpvoted   = "comment_upvoted"
+	ReputationCommentDownvoted = "comment_downvoted"
+	ReputationDownvoteCast     = "downvote_cast"
+	ReputationAnswerAccepted   = "answer_accepted"
+	ReputationAcceptedAnAnswer = "accepted_an_answer"
+)
+
+// reputationWeights are the points of every event kind, each one can be changed with the
+// REPUTATION_<KIND> env var, like REPUTATION_COMMENT_UPVOTED.
+var reputationWeights = map[string]int32{
+	ReputationCommentUpvoted:   envInt32("REPUTATION_COMMENT_UPVOTED", 10),
+	ReputationCommentDownvoted: envInt32("REPUTATION_COMMENT_DOWNVOTED", -2),
+	ReputationDownvoteCast:     envInt32("REPUTATION_DOWNVOTE_CAST", -1),
+	ReputationAnswerAccepted:   envInt32("REPUTATION_ANSWER_ACCEPTED", 15),
+	ReputationAcceptedAnAnswer: envInt32("REPUTATION_ACCEPTED_AN_ANSWER", 2),
+}
+
+// cappedReputationKinds can't earn a user more than reputationDailyCap points a day (UTC),
+// events past the cap are still recorded with the points they actually gave. Reversed events, like undone
+// upvotes, don't count toward the cap.
+var (
+	cappedReputationKinds = []string{ReputationCommentUpvoted}
+	reputationDailyCap    = envInt32("REPUTATION_DAILY_CAP", 200)
+)
+
+func envInt32(key string, fallback int32) int32 {
+	if value, err := strconv.ParseInt(os.Getenv(key), 10, 32); err == nil {
+		return int32(value)
+	}
+	return fallback
+}
+
+// awardReputation appends an event giving userID the points of kind for what actorID did on sourceID.
+// Users don't get reputation from their own actions, and the deleted user placeholder doesn't get any.
+func awardReputation(qtx *repository.Queries, userID, actorID uuid.UUID, kind string, sourceID uuid.UUID) error {
+	if userID == actorID || userID == DeletedUserID {
+		return nil
+	}
+
+	// NOTE: lock the user row, so that concurrent events can't go past the daily cap together.
+	if _, err := qtx.CheckUserID(context.Background(), userID); err != nil {
+		return fmt.Errorf("error locking user: %v", err)
+	}
+
+	points := reputationWeights[kind]
+	if points > 0 && slices.Contains(cappedReputationKinds, kind) {
+		earnedToday, err := qtx.GetUserReputationSince(context.Background(), repository.GetUserReputationSinceParams{
+			UserID: userID,
+			Kinds:  cappedReputationKinds,
+			Since:  time.Now().UTC().Truncate(24 * time.Hour),
+		})
+		if err != nil {
+			return fmt.Errorf("error getting reputation earned today: %v", err)
+		}
+		points = max(0, min(points, reputationDailyCap-earnedToday))
+	}
+
+	return insertReputationEvent(qtx, repository.InsertReputationEventParams{
+		UserID:   userID,
+		ActorID:  uuid.NullUUID{UUID: actorID, Valid: true},
+		Kind:     kind,
+		SourceID: sourceID,
+		Points:   points,
+	})
+}
+
+// revokeReputation appends the reversal of the event of kind that actorID caused on sourceID, if there is one.
+func revokeReputation(qtx *repository.Queries, actorID uuid.UUID, kind string, sourceID uuid.UUID) error {
+	repoEvent, err := qtx.GetActiveReputationEvent(context.Background(), repository.GetActiveReputationEventParams{
+		Kind:     kind,
+		SourceID: sourceID,
+		ActorID:  uuid.NullUUID{UUID: actorID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error getting reputation event: %v", err)
+	}
+
+	return reverseReputationEvent(qtx, repoEvent)
+}
+
+// revokeSourcesReputation reverses every event still standing on sourceIDs, it's used when they are deleted.
+func revokeSourcesReputation(qtx *repository.Queries, sourceIDs []uuid.UUID) error {
+	repoEvents, err := qtx.GetActiveReputationEventsBySources(context.Background(), sourceIDs)
+	if err != nil {
+		return fmt.Errorf("error getting reputation events: %v", err)
+	}
+	for _, repoEvent := range repoEvents {
+		if err := reverseReputationEvent(qtx, repoEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeActorVotesReputation reverses the reputation every vote cast by actorID gave others, it's used when the
+// votes are deleted along with the actor's account.
+func revokeActorVotesReputation(qtx *repository.Queries, actorID uuid.UUID) error {
+	repoEvents, err := qtx.GetActiveReputationEventsByActor(context.Background(), repository.GetActiveReputationEventsByActorParams{
+		ActorID: uuid.NullUUID{UUID: actorID, Valid: true},
+		Kinds:   []string{ReputationCommentUpvoted, ReputationCommentDownvoted},
+	})
+	if err != nil {
+		return fmt.Errorf("error getting reputation events: %v", err)
+	}
+	for _, repoEvent := range repoEvents {
+		if err := reverseReputationEvent(qtx, repoEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reverseReputationEvent(qtx *repository.Queries, repoEvent repository.ReputationEvent) error {
+	return insertReputationEvent(qtx, repository.InsertReputationEventParams{
+		UserID:     repoEvent.UserID,
+		ActorID:    repoEvent.ActorID,
+		Kind:       repoEvent.Kind,
+		SourceID:   repoEvent.SourceID,
+		Points:     -repoEvent.Points,
+		ReversesID: sql.NullInt64{Int64: repoEvent.ID, Valid: true},
+	})
+}
+
+func insertReputationEvent(qtx *repository.Queries, params repository.InsertReputationEventParams) error {
+	if err := qtx.InsertReputationEvent(context.Background(), params); err != nil {
+		return fmt.Errorf("error inserting reputation event: %v", err)
+	}
+	if err := qtx.AddUserReputation(context.Background(), repository.AddUserReputationParams{
+		Reputation: params.Points,
+		ID:         params.UserID,
+	}); err != nil {
+		return fmt.Errorf("error updating user reputation: %v", err)
+	}
+	return nil
+}
+
+// awardVoteReputation gives the comment author, and the voter for downvotes, the reputation of a vote.
+func awardVoteReputation(qtx *repository.Queries, commentID, authorID, voterID uuid.UUID, kind string) error {
+	if kind == "up" {
+		return awardReputation(qtx, authorID, voterID, ReputationCommentUpvoted, commentID)
+	}
+	if err := awardReputation(qtx, authorID, voterID, ReputationCommentDownvoted, commentID); err != nil {
+		return err
+	}
+	if authorID == voterID {
+		return nil
+	}
+	// NOTE: the voter is the actor of their own downvote cost, so award it directly.
+	return insertReputationEvent(qtx, repository.InsertReputationEventParams{
+		UserID:   voterID,
+		ActorID:  uuid.NullUUID{UUID: voterID, Valid: true},
+		Kind:     ReputationDownvoteCast,
+		SourceID: commentID,
+		Points:   reputationWeights[ReputationDownvoteCast],
+	})
+}
+
+func revokeVoteReputation(qtx *repository.Queries, commentID, voterID uuid.UUID, kind string) error {
+	if kind == "up" {
+		return revokeReputation(qtx, voterID, ReputationCommentUpvoted, commentID)
+	}
+	if err := revokeReputation(qtx, voterID, ReputationCommentDownvoted, commentID); err != nil {
+		return err
+	}
+	return revokeReputation(qtx, voterID, ReputationDownvoteCast, commentID)
+}
+
+// awardAnswerReputation gives the reputation of accepting commentID as the answer of a post owned by postOwnerID.
+func awardAnswerReputation(qtx *repository.Queries, commentID, authorID, postOwnerID uuid.UUID) error {
+	if err := awardReputation(qtx, authorID, postOwnerID, ReputationAnswerAccepted, commentID); err != nil {
+		return err
+	}
+	if authorID == postOwnerID {
+		return nil
+	}
+	return insertReputationEvent(qtx, repository.InsertReputationEventParams{
+		UserID:   postOwnerID,
+		ActorID:  uuid.NullUUID{UUID: postOwnerID, Valid: true},
+		Kind:     ReputationAcceptedAnAnswer,
+		SourceID: commentID,
+		Points:   reputationWeights[ReputationAcceptedAnAnswer],
+	})
+}
+
+func revokeAnswerReputation(qtx *repository.Queries, commentID, postOwnerID uuid.UUID) error {
+	if err := revokeReputation(qtx, postOwnerID, ReputationAnswerAccepted, commentID); err != nil {
+		return err
+	}
+	return revokeReputation(qtx, postOwnerID, ReputationAcceptedAnAnswer, commentID)
+}
+
+type ReputationEventPayload struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	SourceID  uuid.UUID `json:"sourceID"`
+	Points    int32     `json:"points"`
+	Reverses  *int64    `json:"reverses,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReputationEventsCursor struct {
+	ID int64 `json:"id"`
+}
+
+func HandleGetUserReputation(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid user id")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor ReputationEventsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	repoUser, err := queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("user not found")
+		}
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	repoEvents, err := queries.GetUserReputationEvents(context.Background(), repository.GetUserReputationEventsParams{
+		UserID: userID,
+		ID:     requestCursor.ID,
+		Limit:  int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting reputation events: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoEvents)
+	if hasMore {
+		responseCursor := ReputationEventsCursor{
+			ID: repoEvents[limit].ID,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoEvents = repoEvents[:limit]
+	}
+
+	events := make([]ReputationEventPayload, 0, len(repoEvents))
+	for _, repoEvent := range repoEvents {
+		event := ReputationEventPayload{
+			ID:        repoEvent.ID,
+			Kind:      repoEvent.Kind,
+			SourceID:  repoEvent.SourceID,
+			Points:    repoEvent.Points,
+			CreatedAt: repoEvent.CreatedAt,
+		}
+		if repoEvent.ReversesID.Valid {
+			event.Reverses = &repoEvent.ReversesID.Int64
+		}
+		events = append(events, event)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"reputation": repoUser.Reputation,
+		"events":     events,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(events),
+	})
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+type PostRevisionPayload struct {
+	PostID      uuid.UUID `json:"postID"`
+	Revision    int32     `json:"revision"`
+	UserID      uuid.UUID `json:"userID"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	EditSummary string    `json:"editSummary,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func newPostRevisionPayload(repoRevision repository.PostRevision) PostRevisionPayload {
+	return PostRevisionPayload{
+		PostID:      repoRevision.PostID,
+		Revision:    repoRevision.Revision,
+		UserID:      repoRevision.UserID,
+		Title:       repoRevision.Title,
+		Content:     repoRevision.Content,
+		EditSummary: repoRevision.EditSummary.String,
+		CreatedAt:   repoRevision.CreatedAt,
+	}
+}
+
+type PostRevisionsCursor struct {
+	Revision int32 `json:"revision"`
+}
+
+func HandleGetPostRevisions(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor PostRevisionsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	if ok, err := queries.CheckPost(context.Background(), postID); err != nil {
+		return fmt.Errorf("error checking post: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("post not found")
+	}
+
+	repoRevisions, err := queries.GetPostRevisions(context.Background(), repository.GetPostRevisionsParams{
+		PostID:   postID,
+		Revision: requestCursor.Revision,
+		Limit:    int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting post revisions: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoRevisions)
+	if hasMore {
+		responseCursor := PostRevisionsCursor{
+			Revision: repoRevisions[limit].Revision,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoRevisions = repoRevisions[:limit]
+	}
+
+	revisions := make([]PostRevisionPayload, 0, len(repoRevisions))
+	for _, repoRevision := range repoRevisions {
+		revisions = append(revisions, newPostRevisionPayload(repoRevision))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revisions":  revisions,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(revisions),
+	})
+}
+
+func HandleGetPostRevision(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	revision, err := c.ParamsInt("revision")
+	if err != nil || revision < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid revision")
+	}
+
+	repoRevision, err := queries.GetPostRevision(context.Background(), repository.GetPostRevisionParams{
+		PostID:   postID,
+		Revision: int32(revision),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("revision not found")
+		}
+		return fmt.Errorf("error getting post revision: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revision": newPostRevisionPayload(repoRevision),
+	})
+}
+
+func HandleGetPostRevisionsDiff(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	from, to := c.QueryInt("from"), c.QueryInt("to")
+	if from < 1 || to < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid revisions, 'from' and 'to' are required")
+	}
+
+	var diff func(old, new string) []utils.DiffOp
+	switch mode := c.Query("mode", DiffModeLine); mode {
+	case DiffModeLine:
+		diff = utils.DiffLines
+	case DiffModeWord:
+		diff = utils.DiffWords
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("invalid mode '%s'", mode))
+	}
+
+	repoRevisions := make([]repository.PostRevision, 0, 2)
+	for _, revision := range []int{from, to} {
+		repoRevision, err := queries.GetPostRevision(context.Background(), repository.GetPostRevisionParams{
+			PostID:   postID,
+			Revision: int32(revision),
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("revision %d not found", revision))
+			}
+			return fmt.Errorf("error getting post revision: %v", err)
+		}
+		repoRevisions = append(repoRevisions, repoRevision)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"from":    newPostRevisionPayload(repoRevisions[0]),
+		"to":      newPostRevisionPayload(repoRevisions[1]),
+		"title":   diff(repoRevisions[0].Title, repoRevisions[1].Title),
+		"content": diff(repoRevisions[0].Content, repoRevisions[1].Content),
+	})
+}
+
+// HandleRollbackPost restores the title and content of an earlier revision, the rollback itself is a new revision
+// so it can be undone like any other edit.
+func HandleRollbackPost(c *fiber.Ctx) error {
+	postID, err := uuid.Parse(c.Params("post_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid post id")
+	}
+
+	revision, err := c.ParamsInt("revision")
+	if err != nil || revision < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid revision")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error bigin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// NOTE: editors can change posts, but only owners and moderators can roll them back.
+	access, err := getPostAccess(c, qtx, postID)
+	if err != nil {
+		return fmt.Errorf("error checking post: %v", err)
+	} else if access == accessNone {
+		return c.Status(fiber.StatusNotFound).SendString("post not found for user")
+	}
+
+	repoRevision, err := qtx.GetPostRevision(context.Background(), repository.GetPostRevisionParams{
+		PostID:   postID,
+		Revision: int32(revision),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).SendString("revision not found")
+		}
+		return fmt.Errorf("error getting post revision: %v", err)
+	}
+
+	repoPost, err := qtx.GetPostByID(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("error getting post: %v", err)
+	}
+	if repoPost.Title == repoRevision.Title && repoPost.Content == repoRevision.Content {
+		return c.Status(fiber.StatusConflict).SendString("post already matches the revision")
+	}
+
+	contentHtml, err := utils.RenderMarkdown(repoRevision.Content)
+	if err != nil {
+		return fmt.Errorf("error rendering content: %v", err)
+	}
+
+	if err := qtx.UpdatePostByID(context.Background(), repository.UpdatePostByIDParams{
+		ID:                 postID,
+		Title:              repoRevision.Title,
+		Content:            repoRevision.Content,
+		ContentHtml:        contentHtml,
+		ContentHtmlVersion: utils.MarkdownRendererVersion,
+	}); err != nil {
+		return fmt.Errorf("error updating post: %v", err)
+	}
+
+	repoNewRevision, err := qtx.InsertPostRevision(context.Background(), repository.InsertPostRevisionParams{
+		PostID:      postID,
+		UserID:      getAuthedUserID(c),
+		Title:       repoRevision.Title,
+		Content:     repoRevision.Content,
+		EditSummary: sql.NullString{String: fmt.Sprintf("Rolled back to revision %d", revision), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error inserting post revision: %v", err)
+	}
+
+	if access == accessModerator {
+		if err := recordModerationAction(c, qtx, ModerationActionRollbackPost, postID); err != nil {
+			return fmt.Errorf("error recording moderation action: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revision": newPostRevisionPayload(repoNewRevision),
+	})
+}
+
+type CommentRevisionPayload struct {
+	CommentID uuid.UUID `json:"commentID"`
+	Revision  int32     `json:"revision"`
+	UserID    uuid.UUID `json:"userID"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CommentRevisionsCursor struct {
+	Revision int32 `json:"revision"`
+}
+
+func HandleGetCommentRevisions(c *fiber.Ctx) error {
+	commentID, err := uuid.Parse(c.Params("comment_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid comment id")
+	}
+
+	limit := c.QueryInt("limit")
+	if limit < 10 || limit > 100 {
+		limit = 10
+	}
+
+	var requestCursor CommentRevisionsCursor
+	if err := decodeBase64AndUnmarshalJson(&requestCursor, c.Query("cursor")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid cursor format")
+	}
+
+	if ok, err := queries.CheckComment(context.Background(), commentID); err != nil {
+		return fmt.Errorf("error checking comment: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("comment not found")
+	}
+
+	repoRevisions, err := queries.GetCommentRevisions(context.Background(), repository.GetCommentRevisionsParams{
+		CommentID: commentID,
+		Revision:  requestCursor.Revision,
+		Limit:     int32(limit) + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting comment revisions: %v", err)
+	}
+
+	var encodedResponseCursor string
+	hasMore := limit < len(repoRevisions)
+	if hasMore {
+		responseCursor := CommentRevisionsCursor{
+			Revision: repoRevisions[limit].Revision,
+		}
+		encodedResponseCursor, err = marshalJsonAndEncodeBase64(responseCursor)
+		if err != nil {
+			return fmt.Errorf("error encoding cursor: %v", err)
+		}
+		repoRevisions = repoRevisions[:limit]
+	}
+
+	revisions := make([]CommentRevisionPayload, 0, len(repoRevisions))
+	for _, repoRevision := range repoRevisions {
+		revisions = append(revisions, CommentRevisionPayload{
+			CommentID: repoRevision.CommentID,
+			Revision:  repoRevision.Revision,
+			UserID:    repoRevision.UserID,
+			Content:   repoRevision.Content,
+			CreatedAt: repoRevision.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"revisions":  revisions,
+		"cursor":     encodedResponseCursor,
+		"hasMore":    hasMore,
+		"totalCount": len(revisions),
+	})
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/repository"
+	"github.com/assaidy/iWonder/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func HandleEnrollTwoFactor(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	repoUser, err := queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+	if repoUser.TotpEnabledAt.Valid {
+		return c.Status(fiber.StatusConflict).SendString("two factor authentication already enabled")
+	}
+
+	secret := utils.GenerateTOTPSecret()
+	if err := queries.SetUserTotpSecret(context.Background(), repository.SetUserTotpSecretParams{
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		ID:         userID,
+	}); err != nil {
+		return fmt.Errorf("error setting user totp secret: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"secret": secret,
+		"uri":    utils.TOTPProvisioningURI(secret, "iWonder", repoUser.Username),
+	})
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+func HandleConfirmTwoFactor(c *fiber.Ctx) error {
+	var req ConfirmTwoFactorRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+	if repoUser.TotpEnabledAt.Valid {
+		return c.Status(fiber.StatusConflict).SendString("two factor authentication already enabled")
+	}
+	if !repoUser.TotpSecret.Valid {
+		return c.Status(fiber.StatusBadRequest).SendString("two factor authentication enrollment not started")
+	}
+
+	step, ok := utils.VerifyTOTP(repoUser.TotpSecret.String, req.Code, time.Now(), repoUser.TotpLastUsedStep)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid code")
+	}
+
+	if err := qtx.EnableUserTotp(context.Background(), repository.EnableUserTotpParams{
+		TotpLastUsedStep: step,
+		ID:               userID,
+	}); err != nil {
+		return fmt.Errorf("error enabling user totp: %v", err)
+	}
+
+	if err := qtx.DeleteUserRecoveryCodes(context.Background(), userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %v", err)
+	}
+	recoveryCodes := utils.GenerateRecoveryCodes(RecoveryCodesCount)
+	for _, code := range recoveryCodes {
+		if err := qtx.InsertRecoveryCode(context.Background(), repository.InsertRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: utils.HashRecoveryCode(code),
+		}); err != nil {
+			return fmt.Errorf("error inserting recovery code: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+type DisableTwoFactorRequest struct {
+	// NOTE: accounts without a password, created through an identity provider, only need the code.
+	Password string `json:"password" validate:"omitempty,customNoOuterSpaces,min=8,max=128"`
+	Code     string `json:"code" validate:"required"`
+}
+
+func HandleDisableTwoFactor(c *fiber.Ctx) error {
+	var req DisableTwoFactorRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+	if !repoUser.TotpEnabledAt.Valid {
+		return c.Status(fiber.StatusConflict).SendString("two factor authentication not enabled")
+	}
+
+	if repoUser.HashedPassword != "" && !utils.VerifyPassword(req.Password, repoUser.HashedPassword) {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid password")
+	}
+
+	if ok, err := verifySecondFactor(qtx, repoUser, req.Code); err != nil {
+		return fmt.Errorf("error verifying second factor: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid code")
+	}
+
+	if err := qtx.DisableUserTotp(context.Background(), userID); err != nil {
+		return fmt.Errorf("error disabling user totp: %v", err)
+	}
+	if err := qtx.DeleteUserRecoveryCodes(context.Background(), userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("two factor authentication disabled successfully")
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+func HandleLoginTwoFactor(c *fiber.Ctx) error {
+	var req LoginTwoFactorRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	claims, err := utils.ParseJWTActionToken(req.ChallengeToken, utils.ActionTwoFactorLogin)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired challenge")
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired challenge")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoChallenge, err := qtx.GetTwoFactorChallenge(context.Background(), challengeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired challenge")
+		}
+		return fmt.Errorf("error getting two factor challenge: %v", err)
+	}
+
+	repoUser, err := qtx.GetUserByID(context.Background(), repoChallenge.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	// NOTE: wrong codes count toward the same lockout as wrong passwords, a new challenge doesn't reset it.
+	userKey := "user:" + strings.ToLower(repoUser.Username)
+	ipKey := "ip:" + c.IP()
+
+	if lockedUntil, err := qtx.GetLoginLockedUntil(context.Background(), []string{userKey, ipKey}); err == nil {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(time.Until(lockedUntil).Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).SendString("too many failed login attempts, try again later")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting login lock: %v", err)
+	}
+
+	if ok, err := verifySecondFactor(qtx, repoUser, req.Code); err != nil {
+		return fmt.Errorf("error verifying second factor: %v", err)
+	} else if !ok {
+		if err := recordLoginFailure(userKey, MaxLoginFailuresPerAccount); err != nil {
+			return fmt.Errorf("error recording login failure: %v", err)
+		}
+		if err := recordLoginFailure(ipKey, MaxLoginFailuresPerIP); err != nil {
+			return fmt.Errorf("error recording login failure: %v", err)
+		}
+		// NOTE: a challenge only allows a few guesses, after that the password has to be entered again.
+		if repoChallenge.FailedAttempts+1 >= MaxTwoFactorChallengeAttempts {
+			if err := qtx.DeleteTwoFactorChallenge(context.Background(), challengeID); err != nil {
+				return fmt.Errorf("error deleting two factor challenge: %v", err)
+			}
+		} else {
+			if err := qtx.IncrementTwoFactorChallengeFailedAttempts(context.Background(), challengeID); err != nil {
+				return fmt.Errorf("error incrementing two factor challenge failed attempts: %v", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error commit tx: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid code")
+	}
+
+	if err := qtx.DeleteTwoFactorChallenge(context.Background(), challengeID); err != nil {
+		return fmt.Errorf("error deleting two factor challenge: %v", err)
+	}
+
+	if err := qtx.DeleteLoginFailures(context.Background(), userKey); err != nil {
+		return fmt.Errorf("error deleting login failures: %v", err)
+	}
+
+	accessToken, refreshToken, err := createSession(c, qtx, repoUser)
+	if err != nil {
+		return fmt.Errorf("error creating session: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":         newUserPayload(repoUser),
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+	})
+}
+
+func createTwoFactorChallenge(userID uuid.UUID) (string, error) {
+	challengeID := uuid.New()
+	expiresAt := time.Now().Add(TwoFactorChallengeExpirationMinutes * time.Minute)
+
+	if err := queries.InsertTwoFactorChallenge(context.Background(), repository.InsertTwoFactorChallengeParams{
+		ID:        challengeID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return utils.GenerateJWTActionToken(utils.ActionClaims{
+		UserID:  userID,
+		Purpose: utils.ActionTwoFactorLogin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code, and consumes it.
+func verifySecondFactor(qtx *repository.Queries, repoUser repository.User, code string) (bool, error) {
+	if step, ok := utils.VerifyTOTP(repoUser.TotpSecret.String, code, time.Now(), repoUser.TotpLastUsedStep); ok {
+		if err := qtx.UpdateUserTotpLastUsedStep(context.Background(), repository.UpdateUserTotpLastUsedStepParams{
+			TotpLastUsedStep: step,
+			ID:               repoUser.ID,
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	affectedRows, err := qtx.UseRecoveryCode(context.Background(), repository.UseRecoveryCodeParams{
+		UserID:   repoUser.ID,
+		CodeHash: utils.HashRecoveryCode(code),
+	})
+	if err != nil {
+		return false, err
+	}
+	return affectedRows > 0, nil
+}
+
+// DeleteExpiredTwoFactorChallenges deletes the challenges of logins whose second factor was never entered in time.
+func DeleteExpiredTwoFactorChallenges() error {
+	if err := queries.DeleteExpiredTwoFactorChallenges(context.Background()); err != nil {
+		return fmt.Errorf("error deleting expired two factor challenges: %v", err)
+	}
+	return nil
+}
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/assaidy/iWonder/internals/db"
+	"github.com/assaidy/iWonder/internals/mail"
 	"github.com/assaidy/iWonder/internals/repository"
 	"github.com/assaidy/iWonder/utils"
 	"github.com/gofiber/fiber/v2"
@@ -15,19 +19,38 @@ import (
 	"github.com/google/uuid"
 )
 
+// dummyHashedPassword is verified against when the user doesn't exist, to take as long as a real verification.
+var dummyHashedPassword, _ = utils.HashPassword("dummy-password")
+
 type UserPayload struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	Bio       string    `json:"bio,omitempty"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"createdAt"`
+	// AvatarURLs maps every size in utils.AvatarSizes to the url of the avatar thumbnail, or of the user's
+	// identicon if they have no avatar.
+	AvatarURLs map[string]string `json:"avatarURLs"`
+	Reputation int32             `json:"reputation"`
+}
+
+func newUserPayload(repoUser repository.User) UserPayload {
+	return UserPayload{
+		ID:         repoUser.ID,
+		Name:       repoUser.Name,
+		Bio:        repoUser.Bio.String,
+		Username:   repoUser.Username,
+		CreatedAt:  repoUser.CreatedAt,
+		AvatarURLs: avatarURLs(repoUser.ID, repoUser.AvatarKey),
+		Reputation: repoUser.Reputation,
+	}
 }
 
 type RegisterRequest struct {
 	Name     string `json:"name" validate:"required,customNoOuterSpaces,max=100"`
 	Bio      string `json:"bio" validate:"customNoOuterSpaces"`
 	Username string `json:"username" validate:"required,customUsername,max=50"`
-	Password string `json:"password" validate:"required,customNoOuterSpaces,min=8,max=50"`
+	Password string `json:"password" validate:"required,customNoOuterSpaces,min=8,max=128"`
 }
 
 func HandleRegister(c *fiber.Ctx) error {
@@ -60,7 +83,7 @@ func HandleRegister(c *fiber.Ctx) error {
 
 type LoginRequest struct {
 	Username string `json:"username" validate:"required,customUsername,max=50"`
-	Password string `json:"password" validate:"required,customNoOuterSpaces,min=8,max=50"`
+	Password string `json:"password" validate:"required,customNoOuterSpaces,min=8,max=128"`
 }
 
 func HandleLogin(c *fiber.Ctx) error {
@@ -69,47 +92,151 @@ func HandleLogin(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	userKey := "user:" + strings.ToLower(req.Username)
+	ipKey := "ip:" + c.IP()
+
+	if lockedUntil, err := queries.GetLoginLockedUntil(context.Background(), []string{userKey, ipKey}); err == nil {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(time.Until(lockedUntil).Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).SendString("too many failed login attempts, try again later")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting login lock: %v", err)
+	}
+
+	// NOTE: unknown users and wrong passwords get the same response, and a password is verified
+	// either way so that the response time doesn't tell them apart either.
+	userFound := true
 	repoUser, err := queries.GetUserByUsername(context.Background(), req.Username)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).SendString("user not found")
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error getting user: %v", err)
 		}
-		return fmt.Errorf("error getting user: %v", err)
+		userFound = false
+		repoUser.HashedPassword = dummyHashedPassword
 	}
 
-	if !utils.VerifyPassword(req.Password, repoUser.HashedPassword) {
-		return c.Status(fiber.StatusUnauthorized).SendString("invalid password")
+	if !utils.VerifyPassword(req.Password, repoUser.HashedPassword) || !userFound {
+		if err := recordLoginFailure(userKey, MaxLoginFailuresPerAccount); err != nil {
+			return fmt.Errorf("error recording login failure: %v", err)
+		}
+		if err := recordLoginFailure(ipKey, MaxLoginFailuresPerIP); err != nil {
+			return fmt.Errorf("error recording login failure: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid username or password")
 	}
 
-	accessToken, err := utils.GenerateJWTAccessToken(utils.JwtClaims{
-		UserID: repoUser.ID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpirationMinutes * time.Minute)),
-		},
+	if utils.PasswordNeedsRehash(repoUser.HashedPassword) {
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			return fmt.Errorf("error hashing password :%v", err)
+		}
+		if err := queries.UpdateUserPassword(context.Background(), repository.UpdateUserPasswordParams{
+			HashedPassword: hashedPassword,
+			ID:             repoUser.ID,
+		}); err != nil {
+			return fmt.Errorf("error updating user password: %v", err)
+		}
+	}
+
+	// NOTE: with two factor enabled the failures are only cleared once the code is verified too,
+	// otherwise every correct password would hand out a fresh set of guesses at the code.
+	if repoUser.TotpEnabledAt.Valid {
+		challengeToken, err := createTwoFactorChallenge(repoUser.ID)
+		if err != nil {
+			return fmt.Errorf("error creating two factor challenge: %v", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"twoFactorRequired": true,
+			"challengeToken":    challengeToken,
+		})
+	}
+
+	if err := queries.DeleteLoginFailures(context.Background(), userKey); err != nil {
+		return fmt.Errorf("error deleting login failures: %v", err)
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	accessToken, refreshToken, err := createSession(c, qtx, repoUser)
+	if err != nil {
+		return fmt.Errorf("error creating session: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"user":         newUserPayload(repoUser),
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+	})
+}
+
+// recordLoginFailure counts a failed login for the key, and once it failed more than maxFailures times
+// within the window, locks it out for a duration that doubles with every further failure.
+func recordLoginFailure(key string, maxFailures int) error {
+	failedAttempts, err := queries.InsertLoginFailure(context.Background(), repository.InsertLoginFailureParams{
+		Key:         key,
+		WindowStart: time.Now().Add(-LoginFailuresWindowHours * time.Hour),
 	})
 	if err != nil {
-		return fmt.Errorf("error creating jwt access token: %v", err)
+		return err
+	}
+	if int(failedAttempts) < maxFailures {
+		return nil
 	}
 
-	refreshToken := utils.GenerateRefreshToken()
-	if err := queries.InsertRefreshToken(context.Background(), repository.InsertRefreshTokenParams{
-		Token:     refreshToken,
+	lockout := LoginLockoutBaseDuration << min(int(failedAttempts)-maxFailures, 16)
+	lockout = min(lockout, LoginLockoutMaxDuration)
+	return queries.LockLoginFailureKey(context.Background(), repository.LockLoginFailureKeyParams{
+		LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+		Key:         key,
+	})
+}
+
+// createSession starts a new session for the user from the request's device and returns its first token pair.
+func createSession(c *fiber.Ctx, qtx *repository.Queries, repoUser repository.User) (accessToken, refreshToken string, err error) {
+	sessionID := uuid.New()
+	if err := qtx.InsertSession(context.Background(), repository.InsertSessionParams{
+		ID:        sessionID,
 		UserID:    repoUser.ID,
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IpAddress: c.IP(),
+	}); err != nil {
+		return "", "", fmt.Errorf("error inserting session: %v", err)
+	}
+
+	refreshToken = utils.GenerateRefreshToken()
+	if err := qtx.InsertRefreshToken(context.Background(), repository.InsertRefreshTokenParams{
+		TokenHash: utils.HashToken(refreshToken),
+		UserID:    repoUser.ID,
+		FamilyID:  sessionID,
 		ExpiresAt: time.Now().Add(time.Hour * 24 * RefreshTokenExpirationDays),
 	}); err != nil {
-		return fmt.Errorf("error creating refresh token: %v", err)
+		return "", "", fmt.Errorf("error inserting refresh token: %v", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user": UserPayload{
-			ID:        repoUser.ID,
-			Name:      repoUser.Name,
-			Bio:       repoUser.Bio.String,
-			Username:  repoUser.Username,
-			CreatedAt: repoUser.CreatedAt,
+	accessToken, err = generateAccessToken(repoUser.ID, sessionID, repoUser.Role)
+	if err != nil {
+		return "", "", fmt.Errorf("error creating jwt access token: %v", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func generateAccessToken(userID, sessionID uuid.UUID, role string) (string, error) {
+	return utils.GenerateJWTAccessToken(utils.JwtClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpirationMinutes * time.Minute)),
 		},
-		"accessToken":  accessToken,
-		"refreshToken": refreshToken,
 	})
 }
 
@@ -119,7 +246,14 @@ func HandleGetAccessToken(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("missing refresh token qeury parameter")
 	}
 
-	repoRefreshToken, err := queries.GetRefreshToken(context.Background(), refreshToken)
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoRefreshToken, err := qtx.GetRefreshToken(context.Background(), utils.HashToken(refreshToken))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return c.Status(fiber.StatusNotFound).SendString("refresh token not found")
@@ -127,22 +261,55 @@ func HandleGetAccessToken(c *fiber.Ctx) error {
 		return fmt.Errorf("error getting refresh token: %v", err)
 	}
 
+	if repoRefreshToken.UsedAt.Valid {
+		// NOTE: a used token should only be in the hands of the client that rotated it.
+		// Seeing it again means it leaked, so we can't tell which client is the legitimate one.
+		if err := qtx.DeleteSession(context.Background(), repoRefreshToken.FamilyID); err != nil {
+			return fmt.Errorf("error deleting session: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error commit tx: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).SendString("refresh token reused, please login again")
+	}
+
 	if repoRefreshToken.ExpiresAt.Sub(time.Now()) < 0 {
 		return c.Status(fiber.StatusUnauthorized).SendString("token expired")
 	}
 
-	accessToken, err := utils.GenerateJWTAccessToken(utils.JwtClaims{
-		UserID: repoRefreshToken.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenExpirationMinutes * time.Minute)),
-		},
-	})
+	if err := qtx.MarkRefreshTokenAsUsed(context.Background(), repoRefreshToken.TokenHash); err != nil {
+		return fmt.Errorf("error marking refresh token as used: %v", err)
+	}
+
+	newRefreshToken := utils.GenerateRefreshToken()
+	if err := qtx.InsertRefreshToken(context.Background(), repository.InsertRefreshTokenParams{
+		TokenHash:       utils.HashToken(newRefreshToken),
+		UserID:          repoRefreshToken.UserID,
+		FamilyID:        repoRefreshToken.FamilyID,
+		ParentTokenHash: sql.NullString{String: repoRefreshToken.TokenHash, Valid: true},
+		ExpiresAt:       time.Now().Add(time.Hour * 24 * RefreshTokenExpirationDays),
+	}); err != nil {
+		return fmt.Errorf("error creating refresh token: %v", err)
+	}
+
+	// NOTE: the role is read again on every refresh, so role changes reach the access tokens.
+	repoUser, err := qtx.GetUserByID(context.Background(), repoRefreshToken.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	accessToken, err := generateAccessToken(repoUser.ID, repoRefreshToken.FamilyID, repoUser.Role)
 	if err != nil {
 		return fmt.Errorf("error creating jwt access token: %v", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx: %v", err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"accessToken": accessToken,
+		"accessToken":  accessToken,
+		"refreshToken": newRefreshToken,
 	})
 }
 
@@ -161,13 +328,7 @@ func HandleGetUserByID(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user": UserPayload{
-			ID:        repoUser.ID,
-			Name:      repoUser.Name,
-			Bio:       repoUser.Bio.String,
-			Username:  repoUser.Username,
-			CreatedAt: repoUser.CreatedAt,
-		},
+		"user": newUserPayload(repoUser),
 	})
 }
 
@@ -183,21 +344,14 @@ func HandleGetUserByUsername(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user": UserPayload{
-			ID:        repoUser.ID,
-			Name:      repoUser.Name,
-			Bio:       repoUser.Bio.String,
-			Username:  repoUser.Username,
-			CreatedAt: repoUser.CreatedAt,
-		},
+		"user": newUserPayload(repoUser),
 	})
 }
 
 type UpdateUserRequest struct {
-	Name     string `json:"name" validate:"required,customNoOuterSpaces,max=100"`
-	Bio      string `json:"bio" validate:"customNoOuterSpaces"`
-	Username string `json:"username" validate:"required,customUsername,max=50"`
-	Password string `json:"password" validate:"required,customNoOuterSpaces,min=8,max=50"`
+	Name     *string `json:"name" validate:"omitnil,min=1,customNoOuterSpaces,max=100"`
+	Bio      *string `json:"bio" validate:"omitnil,customNoOuterSpaces"`
+	Username *string `json:"username" validate:"omitnil,min=1,customUsername,max=50"`
 }
 
 func HandleUpdateUser(c *fiber.Ctx) error {
@@ -215,42 +369,452 @@ func HandleUpdateUser(c *fiber.Ctx) error {
 	defer tx.Rollback()
 	qtx := queries.WithTx(tx)
 
-	if ok, err := qtx.CheckUsernameExceptUserID(context.Background(), repository.CheckUsernameExceptUserIDParams{
-		Username: req.Username,
-		ID:       userID,
-	}); err != nil {
-		return fmt.Errorf("error checking username: %v", err)
-	} else if ok {
-		return c.Status(fiber.StatusConflict).SendString("username already exists")
+	if req.Username != nil {
+		if ok, err := qtx.CheckUsernameExceptUserID(context.Background(), repository.CheckUsernameExceptUserIDParams{
+			Username: *req.Username,
+			ID:       userID,
+		}); err != nil {
+			return fmt.Errorf("error checking username: %v", err)
+		} else if ok {
+			return c.Status(fiber.StatusConflict).SendString("username already exists")
+		}
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	params := repository.UpdateUserByIDParams{ID: userID}
+	if req.Name != nil {
+		params.Name = sql.NullString{String: *req.Name, Valid: true}
+	}
+	if req.Bio != nil {
+		params.SetBio = true
+		params.Bio = sql.NullString{String: *req.Bio, Valid: *req.Bio != ""}
+	}
+	if req.Username != nil {
+		params.Username = sql.NullString{String: *req.Username, Valid: true}
+	}
+
+	if err := qtx.UpdateUserByID(context.Background(), params); err != nil {
+		return fmt.Errorf("error updating user: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx :%v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("user updated successfully")
+}
+
+type UpdatePasswordRequest struct {
+	// NOTE: accounts created through an identity provider have no password yet, they set their first one without it.
+	CurrentPassword string `json:"currentPassword" validate:"omitempty,customNoOuterSpaces,min=8,max=128"`
+	NewPassword     string `json:"newPassword" validate:"required,customNoOuterSpaces,min=8,max=128"`
+}
+
+func HandleUpdatePassword(c *fiber.Ctx) error {
+	var req UpdatePasswordRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("err begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoUser, err := qtx.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	if repoUser.HashedPassword != "" && !utils.VerifyPassword(req.CurrentPassword, repoUser.HashedPassword) {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid password")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
 	if err != nil {
 		return fmt.Errorf("error hashing password :%v", err)
 	}
 
-	err = qtx.UpdateUserByID(context.Background(), repository.UpdateUserByIDParams{
-		ID:             userID,
-		Name:           req.Name,
-		Bio:            sql.NullString{String: req.Bio, Valid: req.Bio != ""},
-		Username:       req.Username,
+	if err := qtx.UpdateUserPassword(context.Background(), repository.UpdateUserPasswordParams{
 		HashedPassword: hashedPassword,
+		ID:             userID,
+	}); err != nil {
+		return fmt.Errorf("error updating user password: %v", err)
+	}
+
+	// NOTE: whoever knew the old password may still hold a session, so only the current one survives.
+	if err := qtx.DeleteUserSessionsExcept(context.Background(), repository.DeleteUserSessionsExceptParams{
+		UserID: userID,
+		ID:     getAuthedSessionID(c),
+	}); err != nil {
+		return fmt.Errorf("error deleting user sessions: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx :%v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("password updated successfully")
+}
+
+func HandleLogout(c *fiber.Ctx) error {
+	if err := queries.DeleteSession(context.Background(), getAuthedSessionID(c)); err != nil {
+		return fmt.Errorf("error deleting session: %v", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type SessionPayload struct {
+	ID          uuid.UUID `json:"id"`
+	UserAgent   string    `json:"userAgent"`
+	IPAddress   string    `json:"ipAddress"`
+	CreatedAt   time.Time `json:"createdAt"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Current     bool      `json:"current"`
+}
+
+func HandleGetSessions(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+	sessionID := getAuthedSessionID(c)
+
+	repoSessions, err := queries.GetUserSessions(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user sessions: %v", err)
+	}
+
+	sessions := make([]SessionPayload, 0, len(repoSessions))
+	for _, repoSession := range repoSessions {
+		sessions = append(sessions, SessionPayload{
+			ID:          repoSession.ID,
+			UserAgent:   repoSession.UserAgent,
+			IPAddress:   repoSession.IpAddress,
+			CreatedAt:   repoSession.CreatedAt,
+			RefreshedAt: repoSession.RefreshedAt,
+			ExpiresAt:   repoSession.ExpiresAt,
+			Current:     repoSession.ID == sessionID,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"sessions": sessions,
 	})
+}
+
+func HandleDeleteSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("session_id"))
 	if err != nil {
-		return fmt.Errorf("error updating user: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString("invalid session id")
+	}
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("error begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if ok, err := qtx.CheckSessionForUser(context.Background(), repository.CheckSessionForUserParams{
+		ID:     sessionID,
+		UserID: userID,
+	}); err != nil {
+		return fmt.Errorf("error checking session: %v", err)
+	} else if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("session not found for user")
+	}
+
+	if err := qtx.DeleteSession(context.Background(), sessionID); err != nil {
+		return fmt.Errorf("error deleting session: %v", err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("error commit tx :%v", err)
+		return fmt.Errorf("error commit tx: %v", err)
 	}
 
-	return c.Status(fiber.StatusOK).SendString("user updated successfully")
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
-func HandleDeleteUser(c *fiber.Ctx) error {
+func HandleDeleteAllSessions(c *fiber.Ctx) error {
 	userID := getAuthedUserID(c)
-	if err := queries.DeleteUserById(context.Background(), userID); err != nil {
-		return fmt.Errorf("error deleting user: %v", err)
+	if err := queries.DeleteUserSessions(context.Background(), userID); err != nil {
+		return fmt.Errorf("error deleting user sessions: %v", err)
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+type UpdateEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+func HandleUpdateEmail(c *fiber.Ctx) error {
+	var req UpdateEmailRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	email := strings.ToLower(req.Email)
+	userID := getAuthedUserID(c)
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("err begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// NOTE: an email is only reserved once it's verified, so that nobody can squat someone else's address.
+	if err := qtx.UpdateUserEmail(context.Background(), repository.UpdateUserEmailParams{
+		Email: sql.NullString{String: email, Valid: true},
+		ID:    userID,
+	}); err != nil {
+		return fmt.Errorf("error updating user email: %v", err)
+	}
+
+	message, err := newEmailVerificationMessage(qtx, userID, email)
+	if err != nil {
+		return fmt.Errorf("error creating email verification message: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx :%v", err)
+	}
+
+	sendMail(message)
+
+	return c.Status(fiber.StatusOK).SendString("email updated successfully, check your inbox to verify it")
+}
+
+func HandleSendEmailVerification(c *fiber.Ctx) error {
+	userID := getAuthedUserID(c)
+
+	repoUser, err := queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
+	}
+	if !repoUser.Email.Valid {
+		return c.Status(fiber.StatusBadRequest).SendString("user has no email")
+	}
+	if repoUser.EmailVerifiedAt.Valid {
+		return c.Status(fiber.StatusConflict).SendString("email already verified")
+	}
+
+	message, err := newEmailVerificationMessage(queries, userID, repoUser.Email.String)
+	if err != nil {
+		return fmt.Errorf("error creating email verification message: %v", err)
+	}
+
+	sendMail(message)
+
+	return c.Status(fiber.StatusOK).SendString("verification email sent successfully")
+}
+
+func HandleVerifyEmail(c *fiber.Ctx) error {
+	claims, err := utils.ParseJWTActionToken(c.Query("token"), utils.ActionVerifyEmail)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+	}
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("err begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoActionToken, err := qtx.UseActionToken(context.Background(), repository.UseActionTokenParams{
+		ID:      tokenID,
+		Purpose: utils.ActionVerifyEmail,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+		}
+		return fmt.Errorf("error using action token: %v", err)
+	}
+
+	if affectedRows, err := qtx.SetUserEmailAsVerified(context.Background(), repository.SetUserEmailAsVerifiedParams{
+		ID:    repoActionToken.UserID,
+		Email: sql.NullString{String: repoActionToken.Email, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error setting user email as verified: %v", err)
+	} else if affectedRows == 0 {
+		return c.Status(fiber.StatusConflict).SendString("email changed since the token was issued or is already in use")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx :%v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("email verified successfully")
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+func HandleForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	// NOTE: the response is the same whether the email belongs to a user or not,
+	// so that this endpoint can't be used to find out who has an account.
+	const response = "if the email belongs to a user, a password reset link has been sent to it"
+
+	email := strings.ToLower(req.Email)
+	repoUser, err := queries.GetUserByVerifiedEmail(context.Background(), sql.NullString{String: email, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusOK).SendString(response)
+		}
+		return fmt.Errorf("error getting user: %v", err)
+	}
+
+	token, err := createActionToken(queries, repoUser.ID, utils.ActionResetPassword, email, PasswordResetTokenExpirationMinutes*time.Minute)
+	if err != nil {
+		return fmt.Errorf("error creating password reset token: %v", err)
+	}
+
+	sendMail(mail.Message{
+		To:      email,
+		Subject: "Reset your iWonder password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your iWonder account '%s'.\n\n"+
+			"Use this link to choose a new password, it expires in %d minutes:\n%s/reset-password?token=%s\n\n"+
+			"If it wasn't you, you can ignore this email.",
+			repoUser.Username, PasswordResetTokenExpirationMinutes, os.Getenv("APP_URL"), token),
+	})
+
+	return c.Status(fiber.StatusOK).SendString(response)
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,customNoOuterSpaces,min=8,max=128"`
+}
+
+func HandleResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := parseAndValidateJsonBody(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	claims, err := utils.ParseJWTActionToken(req.Token, utils.ActionResetPassword)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+	}
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+	}
+
+	tx, err := db.Connection.Begin()
+	if err != nil {
+		return fmt.Errorf("err begin tx: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	repoActionToken, err := qtx.UseActionToken(context.Background(), repository.UseActionTokenParams{
+		ID:      tokenID,
+		Purpose: utils.ActionResetPassword,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).SendString("invalid or expired token")
+		}
+		return fmt.Errorf("error using action token: %v", err)
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("error hashing password :%v", err)
+	}
+
+	if err := qtx.UpdateUserPassword(context.Background(), repository.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             repoActionToken.UserID,
+	}); err != nil {
+		return fmt.Errorf("error updating user password: %v", err)
+	}
+
+	if err := qtx.DeleteUserSessions(context.Background(), repoActionToken.UserID); err != nil {
+		return fmt.Errorf("error deleting user sessions: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit tx :%v", err)
+	}
+
+	return c.Status(fiber.StatusOK).SendString("password reset successfully")
+}
+
+func createActionToken(q *repository.Queries, userID uuid.UUID, purpose, email string, ttl time.Duration) (string, error) {
+	tokenID := uuid.New()
+	expiresAt := time.Now().Add(ttl)
+
+	if err := q.InsertActionToken(context.Background(), repository.InsertActionTokenParams{
+		ID:        tokenID,
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return utils.GenerateJWTActionToken(utils.ActionClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+}
+
+// DeleteExpiredActionTokens deletes the action tokens that can't be used anymore, used or not.
+func DeleteExpiredActionTokens() error {
+	if err := queries.DeleteExpiredActionTokens(context.Background()); err != nil {
+		return fmt.Errorf("error deleting expired action tokens: %v", err)
+	}
+	return nil
+}
+
+// newEmailVerificationMessage returns the mail that verifies the user's email. When another account already verified
+// the email, it tells its owner that instead, so that the response doesn't reveal which emails are registered.
+func newEmailVerificationMessage(q *repository.Queries, userID uuid.UUID, email string) (mail.Message, error) {
+	if taken, err := q.CheckVerifiedEmailExceptUserID(context.Background(), repository.CheckVerifiedEmailExceptUserIDParams{
+		Email: sql.NullString{String: email, Valid: true},
+		ID:    userID,
+	}); err != nil {
+		return mail.Message{}, fmt.Errorf("error checking email: %v", err)
+	} else if taken {
+		return mail.Message{
+			To:      email,
+			Subject: "Your email was added to another iWonder account",
+			Body: "Someone tried to add this email to their iWonder account, but it already belongs to your account.\n\n" +
+				"If it was you, remove it from your account first. Otherwise, you can ignore this email.",
+		}, nil
+	}
+
+	token, err := createActionToken(q, userID, utils.ActionVerifyEmail, email, EmailVerificationTokenExpirationHours*time.Hour)
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("error creating email verification token: %v", err)
+	}
+
+	return mail.Message{
+		To:      email,
+		Subject: "Verify your iWonder email",
+		Body: fmt.Sprintf("Use this link to verify your email, it expires in %d hours:\n%s/verify-email?token=%s",
+			EmailVerificationTokenExpirationHours, os.Getenv("APP_URL"), token),
+	}, nil
+}
@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender writes every message as an .eml file into Dir, for local development and tests.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), msg.To)
+	return os.WriteFile(filepath.Join(s.Dir, name), formatMessage(s.From, msg), 0o644)
+}
+
+// LogSender only logs messages, for local development.
+type LogSender struct{}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	slog.Info("mail sent", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var Default Sender
+
+func init() {
+	switch os.Getenv("MAIL_SENDER") {
+	case "smtp":
+		Default = &SMTPSender{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	case "file":
+		Default = &FileSender{
+			Dir:  os.Getenv("MAIL_DIR"),
+			From: os.Getenv("MAIL_FROM"),
+		}
+	case "log", "":
+		Default = &LogSender{}
+	default:
+		log.Fatal("unknown mail sender: ", os.Getenv("MAIL_SENDER"))
+	}
+}
@@ -0,0 +1,123 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/assaidy/iWonder/internals/oidc"
+	"github.com/assaidy/iWonder/internals/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost/v1/users/oidc/mock/callback"
+
+// authorize follows the provider's authorization url the way a browser would, and returns the code and state
+// it redirects back with.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize got status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProviderLogin(t *testing.T) {
+	user := oidctest.User{
+		Subject:       "subject-1",
+		Email:         "someone@example.com",
+		EmailVerified: true,
+		Name:          "Someone",
+	}
+	server := oidctest.NewServer("iwonder", user)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		// exchange redeems the code the way the test case wants, given what the login started with.
+		exchange func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error)
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			exchange: func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error) {
+				return p.Exchange(context.Background(), code, codeVerifier, nonce)
+			},
+		},
+		{
+			name: "wrong code verifier",
+			exchange: func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error) {
+				return p.Exchange(context.Background(), code, oidc.GenerateRandomString(), nonce)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong nonce",
+			exchange: func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error) {
+				return p.Exchange(context.Background(), code, codeVerifier, oidc.GenerateRandomString())
+			},
+			wantErr: true,
+		},
+		{
+			name: "reused code",
+			exchange: func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error) {
+				if _, err := p.Exchange(context.Background(), code, codeVerifier, nonce); err != nil {
+					return nil, err
+				}
+				return p.Exchange(context.Background(), code, codeVerifier, nonce)
+			},
+			wantErr: true,
+		},
+		{
+			name: "other client",
+			exchange: func(p *oidc.Provider, code, codeVerifier, nonce string) (*oidc.IDTokenClaims, error) {
+				other := server.Provider("other", redirectURL)
+				other.ClientID = "other-client"
+				return other.Exchange(context.Background(), code, codeVerifier, nonce)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := server.Provider("mock", redirectURL)
+			state, nonce, codeVerifier := oidc.GenerateRandomString(), oidc.GenerateRandomString(), oidc.GenerateRandomString()
+
+			authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, codeVerifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, returnedState := authorize(t, authURL)
+			if returnedState != state {
+				t.Fatalf("got state %q back, want %q", returnedState, state)
+			}
+
+			claims, err := tt.exchange(provider, code, codeVerifier, nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("exchange succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != user.Subject || claims.Email != user.Email || !claims.EmailVerified || claims.Name != user.Name {
+				t.Errorf("got claims %+v, want the ones of %+v", claims, user)
+			}
+		})
+	}
+}
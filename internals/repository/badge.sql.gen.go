@@ -0,0 +1,307 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: badge.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getCommentUpvotesBadgeCandidates = `-- name: GetCommentUpvotesBadgeCandidates :many
+select c.user_id, c.id as source_id
+from comments c
+join comment_votes cv on cv.comment_id = c.id
+where
+    cv.kind = 'up' and
+    not exists (
+        select 1 from user_badges ub
+        where ub.user_id = c.user_id and ub.badge = $1 and ub.source_key = c.id::varchar
+    )
+group by c.id
+having count(*) >= $2::int
+`
+
+type GetCommentUpvotesBadgeCandidatesParams struct {
+	Badge      string
+	MinUpvotes int32
+}
+
+type GetCommentUpvotesBadgeCandidatesRow struct {
+	UserID   uuid.UUID
+	SourceID uuid.UUID
+}
+
+func (q *Queries) GetCommentUpvotesBadgeCandidates(ctx context.Context, arg GetCommentUpvotesBadgeCandidatesParams) ([]GetCommentUpvotesBadgeCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentUpvotesBadgeCandidates, arg.Badge, arg.MinUpvotes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentUpvotesBadgeCandidatesRow
+	for rows.Next() {
+		var i GetCommentUpvotesBadgeCandidatesRow
+		if err := rows.Scan(&i.UserID, &i.SourceID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFirstAcceptedAnswerBadgeCandidates = `-- name: GetFirstAcceptedAnswerBadgeCandidates :many
+select distinct on (c.user_id) c.user_id, c.id as source_id
+from post_answers pa
+join comments c on c.id = pa.comment_id
+join posts p on p.id = pa.post_id
+where
+    c.user_id != p.user_id and
+    not exists (select 1 from user_badges ub where ub.user_id = c.user_id and ub.badge = $1)
+order by c.user_id, c.created_at
+`
+
+type GetFirstAcceptedAnswerBadgeCandidatesRow struct {
+	UserID   uuid.UUID
+	SourceID uuid.UUID
+}
+
+func (q *Queries) GetFirstAcceptedAnswerBadgeCandidates(ctx context.Context, badge string) ([]GetFirstAcceptedAnswerBadgeCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFirstAcceptedAnswerBadgeCandidates, badge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFirstAcceptedAnswerBadgeCandidatesRow
+	for rows.Next() {
+		var i GetFirstAcceptedAnswerBadgeCandidatesRow
+		if err := rows.Scan(&i.UserID, &i.SourceID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFirstPostBadgeCandidates = `-- name: GetFirstPostBadgeCandidates :many
+select distinct on (p.user_id) p.user_id, p.id as source_id
+from posts p
+where not exists (select 1 from user_badges ub where ub.user_id = p.user_id and ub.badge = $1)
+order by p.user_id, p.created_at
+`
+
+type GetFirstPostBadgeCandidatesRow struct {
+	UserID   uuid.UUID
+	SourceID uuid.UUID
+}
+
+func (q *Queries) GetFirstPostBadgeCandidates(ctx context.Context, badge string) ([]GetFirstPostBadgeCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFirstPostBadgeCandidates, badge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFirstPostBadgeCandidatesRow
+	for rows.Next() {
+		var i GetFirstPostBadgeCandidatesRow
+		if err := rows.Scan(&i.UserID, &i.SourceID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentBadgeAwards = `-- name: GetRecentBadgeAwards :many
+select ub.id, ub.user_id, ub.badge, ub.source_id, ub.source_key, ub.awarded_at, u.username
+from user_badges ub
+join users u on u.id = ub.user_id
+where ub.id <= coalesce(nullif($2::bigint, 0), 9223372036854775807)
+order by ub.id desc
+limit $1
+`
+
+type GetRecentBadgeAwardsParams struct {
+	Limit int32
+	ID    int64
+}
+
+type GetRecentBadgeAwardsRow struct {
+	ID        int64
+	UserID    uuid.UUID
+	Badge     string
+	SourceID  uuid.NullUUID
+	SourceKey string
+	AwardedAt time.Time
+	Username  string
+}
+
+func (q *Queries) GetRecentBadgeAwards(ctx context.Context, arg GetRecentBadgeAwardsParams) ([]GetRecentBadgeAwardsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentBadgeAwards, arg.Limit, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentBadgeAwardsRow
+	for rows.Next() {
+		var i GetRecentBadgeAwardsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Badge,
+			&i.SourceID,
+			&i.SourceKey,
+			&i.AwardedAt,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagPostsBadgeCandidates = `-- name: GetTagPostsBadgeCandidates :many
+select p.user_id, t.name as tag
+from posts p
+join post_tags pt on pt.post_id = p.id
+join tags t on t.id = pt.tag_id
+where not exists (
+    select 1 from user_badges ub
+    where ub.user_id = p.user_id and ub.badge = $1 and ub.source_key = t.name
+)
+group by p.user_id, t.name
+having count(*) >= $2::int
+`
+
+type GetTagPostsBadgeCandidatesParams struct {
+	Badge    string
+	MinPosts int32
+}
+
+type GetTagPostsBadgeCandidatesRow struct {
+	UserID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) GetTagPostsBadgeCandidates(ctx context.Context, arg GetTagPostsBadgeCandidatesParams) ([]GetTagPostsBadgeCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagPostsBadgeCandidates, arg.Badge, arg.MinPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagPostsBadgeCandidatesRow
+	for rows.Next() {
+		var i GetTagPostsBadgeCandidatesRow
+		if err := rows.Scan(&i.UserID, &i.Tag); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserBadges = `-- name: GetUserBadges :many
+select id, user_id, badge, source_id, source_key, awarded_at
+from user_badges
+where
+    user_id = $1 and
+    id <= coalesce(nullif($3::bigint, 0), 9223372036854775807)
+order by id desc
+limit $2
+`
+
+type GetUserBadgesParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	ID     int64
+}
+
+func (q *Queries) GetUserBadges(ctx context.Context, arg GetUserBadgesParams) ([]UserBadge, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBadges, arg.UserID, arg.Limit, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserBadge
+	for rows.Next() {
+		var i UserBadge
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Badge,
+			&i.SourceID,
+			&i.SourceKey,
+			&i.AwardedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertUserBadge = `-- name: InsertUserBadge :execrows
+insert into user_badges (user_id, badge, source_id, source_key)
+values ($1, $2, $3, $4)
+on conflict (user_id, badge, source_key) do nothing
+`
+
+type InsertUserBadgeParams struct {
+	UserID    uuid.UUID
+	Badge     string
+	SourceID  uuid.NullUUID
+	SourceKey string
+}
+
+func (q *Queries) InsertUserBadge(ctx context.Context, arg InsertUserBadgeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, insertUserBadge,
+		arg.UserID,
+		arg.Badge,
+		arg.SourceID,
+		arg.SourceKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,186 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: block.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkCommentBlockedForUser = `-- name: CheckCommentBlockedForUser :one
+select exists (
+    select 1
+    from comments c
+    join posts p on p.id = c.post_id
+    join user_blocks b on b.blocker_id in (c.user_id, p.user_id)
+    where c.id = $1 and b.blocked_id = $2 and b.kind = 'block'
+)
+`
+
+type CheckCommentBlockedForUserParams struct {
+	ID        uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CheckCommentBlockedForUser(ctx context.Context, arg CheckCommentBlockedForUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkCommentBlockedForUser, arg.ID, arg.BlockedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const checkPostBlockedForUser = `-- name: CheckPostBlockedForUser :one
+select exists (
+    select 1
+    from user_blocks b
+    join posts p on p.user_id = b.blocker_id
+    where p.id = $1 and b.blocked_id = $2 and b.kind = 'block'
+)
+`
+
+type CheckPostBlockedForUserParams struct {
+	ID        uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CheckPostBlockedForUser(ctx context.Context, arg CheckPostBlockedForUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkPostBlockedForUser, arg.ID, arg.BlockedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const checkUsersBlocked = `-- name: CheckUsersBlocked :one
+select exists (
+    select 1
+    from user_blocks
+    where
+        kind = 'block' and
+        ((blocker_id = $1 and blocked_id = $2) or (blocker_id = $2 and blocked_id = $1))
+)
+`
+
+type CheckUsersBlockedParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CheckUsersBlocked(ctx context.Context, arg CheckUsersBlockedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkUsersBlocked, arg.BlockerID, arg.BlockedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deleteUserBlock = `-- name: DeleteUserBlock :execrows
+delete from user_blocks where blocker_id = $1 and blocked_id = $2 and kind = $3
+`
+
+type DeleteUserBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	Kind      string
+}
+
+func (q *Queries) DeleteUserBlock(ctx context.Context, arg DeleteUserBlockParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserBlock, arg.BlockerID, arg.BlockedID, arg.Kind)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserBlocks = `-- name: GetUserBlocks :many
+select u.id, u.name, u.bio, u.username, u.hashed_password, u.created_at, u.email, u.email_verified_at, u.totp_secret, u.totp_enabled_at, u.totp_last_used_step, u.role, u.deletion_scheduled_at, u.avatar_key, u.reputation, b.created_at as blocked_at
+from user_blocks b
+join users u on u.id = b.blocked_id
+where
+    b.blocker_id = $1 and
+    b.kind = $2 and
+    b.created_at <= coalesce(
+        nullif($4::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by b.created_at desc
+limit $3
+`
+
+type GetUserBlocksParams struct {
+	BlockerID uuid.UUID
+	Kind      string
+	Limit     int32
+	CreatedAt time.Time
+}
+
+type GetUserBlocksRow struct {
+	User      User
+	BlockedAt time.Time
+}
+
+func (q *Queries) GetUserBlocks(ctx context.Context, arg GetUserBlocksParams) ([]GetUserBlocksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBlocks,
+		arg.BlockerID,
+		arg.Kind,
+		arg.Limit,
+		arg.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserBlocksRow
+	for rows.Next() {
+		var i GetUserBlocksRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Name,
+			&i.User.Bio,
+			&i.User.Username,
+			&i.User.HashedPassword,
+			&i.User.CreatedAt,
+			&i.User.Email,
+			&i.User.EmailVerifiedAt,
+			&i.User.TotpSecret,
+			&i.User.TotpEnabledAt,
+			&i.User.TotpLastUsedStep,
+			&i.User.Role,
+			&i.User.DeletionScheduledAt,
+			&i.User.AvatarKey,
+			&i.User.Reputation,
+			&i.BlockedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertUserBlock = `-- name: UpsertUserBlock :exec
+insert into user_blocks (blocker_id, blocked_id, kind)
+values ($1, $2, $3)
+on conflict (blocker_id, blocked_id) do update
+    set kind = excluded.kind, created_at = now()
+`
+
+type UpsertUserBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	Kind      string
+}
+
+func (q *Queries) UpsertUserBlock(ctx context.Context, arg UpsertUserBlockParams) error {
+	_, err := q.db.ExecContext(ctx, upsertUserBlock, arg.BlockerID, arg.BlockedID, arg.Kind)
+	return err
+}
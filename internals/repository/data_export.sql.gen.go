@@ -0,0 +1,371 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: data_export.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkPendingDataExportForUser = `-- name: CheckPendingDataExportForUser :one
+select exists (
+    select 1 from data_exports
+    where user_id = $1 and status = 'pending' and created_at > $2
+)
+`
+
+type CheckPendingDataExportForUserParams struct {
+	UserID       uuid.UUID
+	PendingSince time.Time
+}
+
+// NOTE: exports that stayed pending since before pending_since were interrupted by a restart, they don't block new ones.
+func (q *Queries) CheckPendingDataExportForUser(ctx context.Context, arg CheckPendingDataExportForUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkPendingDataExportForUser, arg.UserID, arg.PendingSince)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deleteExpiredDataExports = `-- name: DeleteExpiredDataExports :many
+delete from data_exports
+where
+    expires_at <= now() or
+    (status = 'pending' and created_at <= $1)
+returning file_path
+`
+
+func (q *Queries) DeleteExpiredDataExports(ctx context.Context, pendingSince time.Time) ([]sql.NullString, error) {
+	rows, err := q.db.QueryContext(ctx, deleteExpiredDataExports, pendingSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullString
+	for rows.Next() {
+		var file_path sql.NullString
+		if err := rows.Scan(&file_path); err != nil {
+			return nil, err
+		}
+		items = append(items, file_path)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteUserDataExports = `-- name: DeleteUserDataExports :many
+delete from data_exports
+where user_id = $1
+returning file_path
+`
+
+func (q *Queries) DeleteUserDataExports(ctx context.Context, userID uuid.UUID) ([]sql.NullString, error) {
+	rows, err := q.db.QueryContext(ctx, deleteUserDataExports, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullString
+	for rows.Next() {
+		var file_path sql.NullString
+		if err := rows.Scan(&file_path); err != nil {
+			return nil, err
+		}
+		items = append(items, file_path)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllUserCommentVotes = `-- name: GetAllUserCommentVotes :many
+select comment_id, user_id, kind from comment_votes where user_id = $1
+`
+
+func (q *Queries) GetAllUserCommentVotes(ctx context.Context, userID uuid.UUID) ([]CommentVote, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserCommentVotes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CommentVote
+	for rows.Next() {
+		var i CommentVote
+		if err := rows.Scan(&i.CommentID, &i.UserID, &i.Kind); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllUserComments = `-- name: GetAllUserComments :many
+select id, post_id, user_id, content, created_at, edited_at, content_html, content_html_version, up_count, down_count, score from comments where user_id = $1 order by created_at
+`
+
+func (q *Queries) GetAllUserComments(ctx context.Context, userID uuid.UUID) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserComments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllUserPostVotes = `-- name: GetAllUserPostVotes :many
+select post_id, user_id, kind from post_votes where user_id = $1
+`
+
+func (q *Queries) GetAllUserPostVotes(ctx context.Context, userID uuid.UUID) ([]PostVote, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserPostVotes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostVote
+	for rows.Next() {
+		var i PostVote
+		if err := rows.Scan(&i.PostID, &i.UserID, &i.Kind); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllUserPosts = `-- name: GetAllUserPosts :many
+select id, user_id, title, content, answered, created_at, content_html, content_html_version, up_count, down_count, score from posts where user_id = $1 order by created_at
+`
+
+func (q *Queries) GetAllUserPosts(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserPosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDataExportForUser = `-- name: GetDataExportForUser :one
+select id, user_id, status, file_path, created_at, expires_at from data_exports where id = $1 and user_id = $2
+`
+
+type GetDataExportForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDataExportForUser(ctx context.Context, arg GetDataExportForUserParams) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, getDataExportForUser, arg.ID, arg.UserID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.FilePath,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getTagsCreatedByUser = `-- name: GetTagsCreatedByUser :many
+select id, name, created_at, created_by from tags where created_by = $1 order by created_at
+`
+
+func (q *Queries) GetTagsCreatedByUser(ctx context.Context, createdBy uuid.NullUUID) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsCreatedByUser, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAcceptedAnswers = `-- name: GetUserAcceptedAnswers :many
+select pa.post_id, pa.comment_id, p.user_id = $1 as accepted_by_user
+from post_answers pa
+join posts p on p.id = pa.post_id
+join comments c on c.id = pa.comment_id
+where p.user_id = $1 or c.user_id = $1
+`
+
+type GetUserAcceptedAnswersRow struct {
+	PostID         uuid.UUID
+	CommentID      uuid.UUID
+	AcceptedByUser bool
+}
+
+func (q *Queries) GetUserAcceptedAnswers(ctx context.Context, userID uuid.UUID) ([]GetUserAcceptedAnswersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAcceptedAnswers, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserAcceptedAnswersRow
+	for rows.Next() {
+		var i GetUserAcceptedAnswersRow
+		if err := rows.Scan(&i.PostID, &i.CommentID, &i.AcceptedByUser); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertDataExport = `-- name: InsertDataExport :exec
+insert into data_exports (id, user_id)
+values ($1, $2)
+`
+
+type InsertDataExportParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) InsertDataExport(ctx context.Context, arg InsertDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, insertDataExport, arg.ID, arg.UserID)
+	return err
+}
+
+const setDataExportAsFailed = `-- name: SetDataExportAsFailed :exec
+update data_exports
+set
+    status = 'failed',
+    expires_at = $1
+where id = $2
+`
+
+type SetDataExportAsFailedParams struct {
+	ExpiresAt sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetDataExportAsFailed(ctx context.Context, arg SetDataExportAsFailedParams) error {
+	_, err := q.db.ExecContext(ctx, setDataExportAsFailed, arg.ExpiresAt, arg.ID)
+	return err
+}
+
+const setDataExportAsReady = `-- name: SetDataExportAsReady :exec
+update data_exports
+set
+    status = 'ready',
+    file_path = $1,
+    expires_at = $2
+where id = $3
+`
+
+type SetDataExportAsReadyParams struct {
+	FilePath  sql.NullString
+	ExpiresAt sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetDataExportAsReady(ctx context.Context, arg SetDataExportAsReadyParams) error {
+	_, err := q.db.ExecContext(ctx, setDataExportAsReady, arg.FilePath, arg.ExpiresAt, arg.ID)
+	return err
+}
@@ -0,0 +1,272 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: follow.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFollow = `-- name: DeleteFollow :execrows
+delete from follows where follower_id = $1 and followee_id = $2
+`
+
+type DeleteFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollow, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollowsBetween = `-- name: DeleteFollowsBetween :exec
+delete from follows
+where
+    (follower_id = $1 and followee_id = $2) or
+    (follower_id = $2 and followee_id = $1)
+`
+
+type DeleteFollowsBetweenParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) DeleteFollowsBetween(ctx context.Context, arg DeleteFollowsBetweenParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowsBetween, arg.FollowerID, arg.FolloweeID)
+	return err
+}
+
+const getFollowingFeed = `-- name: GetFollowingFeed :many
+select kind, id, post_id, user_id, title, content, content_html, created_at
+from (
+    select 'post'::varchar as kind, p.id, p.id as post_id, p.user_id, p.title, p.content, p.content_html, p.created_at
+    from posts p
+    join follows f on f.followee_id = p.user_id
+    where f.follower_id = $2
+    union all
+    select 'answer'::varchar as kind, c.id, c.post_id, c.user_id, p.title, c.content, c.content_html, c.created_at
+    from comments c
+    join posts p on p.id = c.post_id
+    join follows f on f.followee_id = c.user_id
+    where f.follower_id = $2
+) feed
+where
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = $2 and b.blocked_id = feed.user_id and b.kind = 'mute'
+    ) and
+    feed.created_at <= coalesce(
+        nullif($3::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by feed.created_at desc
+limit $1
+`
+
+type GetFollowingFeedParams struct {
+	Limit      int32
+	FollowerID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type GetFollowingFeedRow struct {
+	Kind        string
+	ID          uuid.UUID
+	PostID      uuid.UUID
+	UserID      uuid.UUID
+	Title       string
+	Content     string
+	ContentHtml string
+	CreatedAt   time.Time
+}
+
+func (q *Queries) GetFollowingFeed(ctx context.Context, arg GetFollowingFeedParams) ([]GetFollowingFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowingFeed, arg.Limit, arg.FollowerID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowingFeedRow
+	for rows.Next() {
+		var i GetFollowingFeedRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.ContentHtml,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFollowers = `-- name: GetUserFollowers :many
+select u.id, u.name, u.bio, u.username, u.hashed_password, u.created_at, u.email, u.email_verified_at, u.totp_secret, u.totp_enabled_at, u.totp_last_used_step, u.role, u.deletion_scheduled_at, u.avatar_key, u.reputation, f.created_at as followed_at
+from follows f
+join users u on u.id = f.follower_id
+where
+    f.followee_id = $1 and
+    f.created_at <= coalesce(
+        nullif($3::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by f.created_at desc
+limit $2
+`
+
+type GetUserFollowersParams struct {
+	FolloweeID uuid.UUID
+	Limit      int32
+	CreatedAt  time.Time
+}
+
+type GetUserFollowersRow struct {
+	User       User
+	FollowedAt time.Time
+}
+
+func (q *Queries) GetUserFollowers(ctx context.Context, arg GetUserFollowersParams) ([]GetUserFollowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFollowers, arg.FolloweeID, arg.Limit, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserFollowersRow
+	for rows.Next() {
+		var i GetUserFollowersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Name,
+			&i.User.Bio,
+			&i.User.Username,
+			&i.User.HashedPassword,
+			&i.User.CreatedAt,
+			&i.User.Email,
+			&i.User.EmailVerifiedAt,
+			&i.User.TotpSecret,
+			&i.User.TotpEnabledAt,
+			&i.User.TotpLastUsedStep,
+			&i.User.Role,
+			&i.User.DeletionScheduledAt,
+			&i.User.AvatarKey,
+			&i.User.Reputation,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFollowing = `-- name: GetUserFollowing :many
+select u.id, u.name, u.bio, u.username, u.hashed_password, u.created_at, u.email, u.email_verified_at, u.totp_secret, u.totp_enabled_at, u.totp_last_used_step, u.role, u.deletion_scheduled_at, u.avatar_key, u.reputation, f.created_at as followed_at
+from follows f
+join users u on u.id = f.followee_id
+where
+    f.follower_id = $1 and
+    f.created_at <= coalesce(
+        nullif($3::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by f.created_at desc
+limit $2
+`
+
+type GetUserFollowingParams struct {
+	FollowerID uuid.UUID
+	Limit      int32
+	CreatedAt  time.Time
+}
+
+type GetUserFollowingRow struct {
+	User       User
+	FollowedAt time.Time
+}
+
+func (q *Queries) GetUserFollowing(ctx context.Context, arg GetUserFollowingParams) ([]GetUserFollowingRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFollowing, arg.FollowerID, arg.Limit, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserFollowingRow
+	for rows.Next() {
+		var i GetUserFollowingRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Name,
+			&i.User.Bio,
+			&i.User.Username,
+			&i.User.HashedPassword,
+			&i.User.CreatedAt,
+			&i.User.Email,
+			&i.User.EmailVerifiedAt,
+			&i.User.TotpSecret,
+			&i.User.TotpEnabledAt,
+			&i.User.TotpLastUsedStep,
+			&i.User.Role,
+			&i.User.DeletionScheduledAt,
+			&i.User.AvatarKey,
+			&i.User.Reputation,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertFollow = `-- name: InsertFollow :execrows
+insert into follows (follower_id, followee_id)
+values ($1, $2)
+on conflict (follower_id, followee_id) do nothing
+`
+
+type InsertFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) InsertFollow(ctx context.Context, arg InsertFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, insertFollow, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
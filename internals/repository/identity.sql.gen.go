@@ -0,0 +1,154 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: identity.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteExpiredOidcLoginCodes = `-- name: DeleteExpiredOidcLoginCodes :exec
+delete from oidc_login_codes where expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredOidcLoginCodes(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredOidcLoginCodes)
+	return err
+}
+
+const deleteExpiredOidcLoginStates = `-- name: DeleteExpiredOidcLoginStates :exec
+delete from oidc_login_states where expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredOidcLoginStates(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredOidcLoginStates)
+	return err
+}
+
+const deleteOidcLoginCode = `-- name: DeleteOidcLoginCode :one
+delete from oidc_login_codes
+where code_hash = $1 and expires_at > now()
+returning user_id
+`
+
+func (q *Queries) DeleteOidcLoginCode(ctx context.Context, codeHash string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteOidcLoginCode, codeHash)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const deleteOidcLoginState = `-- name: DeleteOidcLoginState :one
+delete from oidc_login_states
+where state = $1 and provider = $2 and expires_at > now()
+returning state, provider, nonce, code_verifier, created_at, expires_at
+`
+
+type DeleteOidcLoginStateParams struct {
+	State    string
+	Provider string
+}
+
+func (q *Queries) DeleteOidcLoginState(ctx context.Context, arg DeleteOidcLoginStateParams) (OidcLoginState, error) {
+	row := q.db.QueryRowContext(ctx, deleteOidcLoginState, arg.State, arg.Provider)
+	var i OidcLoginState
+	err := row.Scan(
+		&i.State,
+		&i.Provider,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getUserIdentity = `-- name: GetUserIdentity :one
+select provider, subject, user_id, email, created_at from user_identities where provider = $1 and subject = $2
+`
+
+type GetUserIdentityParams struct {
+	Provider string
+	Subject  string
+}
+
+func (q *Queries) GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, getUserIdentity, arg.Provider, arg.Subject)
+	var i UserIdentity
+	err := row.Scan(
+		&i.Provider,
+		&i.Subject,
+		&i.UserID,
+		&i.Email,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertOidcLoginCode = `-- name: InsertOidcLoginCode :exec
+insert into oidc_login_codes (code_hash, user_id, expires_at)
+values ($1, $2, $3)
+`
+
+type InsertOidcLoginCodeParams struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) InsertOidcLoginCode(ctx context.Context, arg InsertOidcLoginCodeParams) error {
+	_, err := q.db.ExecContext(ctx, insertOidcLoginCode, arg.CodeHash, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const insertOidcLoginState = `-- name: InsertOidcLoginState :exec
+insert into oidc_login_states (state, provider, nonce, code_verifier, expires_at)
+values ($1, $2, $3, $4, $5)
+`
+
+type InsertOidcLoginStateParams struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+func (q *Queries) InsertOidcLoginState(ctx context.Context, arg InsertOidcLoginStateParams) error {
+	_, err := q.db.ExecContext(ctx, insertOidcLoginState,
+		arg.State,
+		arg.Provider,
+		arg.Nonce,
+		arg.CodeVerifier,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const insertUserIdentity = `-- name: InsertUserIdentity :exec
+insert into user_identities (provider, subject, user_id, email)
+values ($1, $2, $3, $4)
+`
+
+type InsertUserIdentityParams struct {
+	Provider string
+	Subject  string
+	UserID   uuid.UUID
+	Email    sql.NullString
+}
+
+func (q *Queries) InsertUserIdentity(ctx context.Context, arg InsertUserIdentityParams) error {
+	_, err := q.db.ExecContext(ctx, insertUserIdentity,
+		arg.Provider,
+		arg.Subject,
+		arg.UserID,
+		arg.Email,
+	)
+	return err
+}
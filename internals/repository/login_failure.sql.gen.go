@@ -0,0 +1,79 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: login_failure.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const deleteLoginFailures = `-- name: DeleteLoginFailures :exec
+delete from login_failures where key = $1
+`
+
+func (q *Queries) DeleteLoginFailures(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginFailures, key)
+	return err
+}
+
+const getLoginLockedUntil = `-- name: GetLoginLockedUntil :one
+select locked_until::timestamptz
+from login_failures
+where key = any($1::varchar[]) and locked_until > now()
+order by locked_until desc
+limit 1
+`
+
+func (q *Queries) GetLoginLockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLoginLockedUntil, pq.Array(keys))
+	var locked_until time.Time
+	err := row.Scan(&locked_until)
+	return locked_until, err
+}
+
+const insertLoginFailure = `-- name: InsertLoginFailure :one
+insert into login_failures (key, failed_attempts, last_failed_at)
+values ($1, 1, now())
+on conflict (key) do update
+    set
+        failed_attempts = case
+            when login_failures.last_failed_at < $2::timestamptz then 1
+            else login_failures.failed_attempts + 1
+        end,
+        last_failed_at = excluded.last_failed_at
+returning failed_attempts
+`
+
+type InsertLoginFailureParams struct {
+	Key         string
+	WindowStart time.Time
+}
+
+func (q *Queries) InsertLoginFailure(ctx context.Context, arg InsertLoginFailureParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, insertLoginFailure, arg.Key, arg.WindowStart)
+	var failed_attempts int32
+	err := row.Scan(&failed_attempts)
+	return failed_attempts, err
+}
+
+const lockLoginFailureKey = `-- name: LockLoginFailureKey :exec
+update login_failures
+set locked_until = $1
+where key = $2
+`
+
+type LockLoginFailureKeyParams struct {
+	LockedUntil sql.NullTime
+	Key         string
+}
+
+func (q *Queries) LockLoginFailureKey(ctx context.Context, arg LockLoginFailureKeyParams) error {
+	_, err := q.db.ExecContext(ctx, lockLoginFailureKey, arg.LockedUntil, arg.Key)
+	return err
+}
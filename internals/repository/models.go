@@ -11,9 +11,33 @@ import (
 	"github.com/google/uuid"
 )
 
-type Comment struct {
+type ActionToken struct {
 	ID        uuid.UUID
-	PostID    uuid.UUID
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Comment struct {
+	ID                 uuid.UUID
+	PostID             uuid.UUID
+	UserID             uuid.UUID
+	Content            string
+	CreatedAt          time.Time
+	EditedAt           sql.NullTime
+	ContentHtml        string
+	ContentHtmlVersion int32
+	UpCount            int32
+	DownCount          int32
+	Score              int32
+}
+
+type CommentRevision struct {
+	CommentID uuid.UUID
+	Revision  int32
 	UserID    uuid.UUID
 	Content   string
 	CreatedAt time.Time
@@ -25,18 +49,91 @@ type CommentVote struct {
 	Kind      string
 }
 
-type Post struct {
+type DataExport struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
-	Title     string
-	Content   string
-	Answered  bool
+	Status    string
+	FilePath  sql.NullString
 	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginFailure struct {
+	Key            string
+	FailedAttempts int32
+	LastFailedAt   time.Time
+	LockedUntil    sql.NullTime
+}
+
+type ModerationAction struct {
+	ID        int32
+	ActorID   uuid.UUID
+	Action    string
+	TargetID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type OidcLoginCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type OidcLoginState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+type Post struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	Title              string
+	Content            string
+	Answered           bool
+	CreatedAt          time.Time
+	ContentHtml        string
+	ContentHtmlVersion int32
+	UpCount            int32
+	DownCount          int32
+	Score              int32
 }
 
 type PostAnswer struct {
-	PostID    uuid.UUID
-	CommentID uuid.UUID
+	PostID     uuid.UUID
+	CommentID  uuid.UUID
+	AcceptedAt time.Time
+}
+
+type PostRevision struct {
+	PostID      uuid.UUID
+	Revision    int32
+	UserID      uuid.UUID
+	Title       string
+	Content     string
+	EditSummary sql.NullString
+	CreatedAt   time.Time
 }
 
 type PostTag struct {
@@ -44,24 +141,100 @@ type PostTag struct {
 	TagID  int32
 }
 
+type PostVote struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+	Kind   string
+}
+
+type RecoveryCode struct {
+	UserID   uuid.UUID
+	CodeHash string
+	UsedAt   sql.NullTime
+}
+
 type RefreshToken struct {
-	Token     string
+	TokenHash       string
+	UserID          uuid.UUID
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+	FamilyID        uuid.UUID
+	ParentTokenHash sql.NullString
+	UsedAt          sql.NullTime
+}
+
+type ReputationEvent struct {
+	ID         int64
+	UserID     uuid.UUID
+	ActorID    uuid.NullUUID
+	Kind       string
+	SourceID   uuid.UUID
+	Points     int32
+	ReversesID sql.NullInt64
+	CreatedAt  time.Time
+}
+
+type Session struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	UserAgent string
+	IpAddress string
 	CreatedAt time.Time
-	ExpiresAt time.Time
 }
 
 type Tag struct {
 	ID        int32
 	Name      string
 	CreatedAt time.Time
+	CreatedBy uuid.NullUUID
 }
 
-type User struct {
+type TwoFactorChallenge struct {
 	ID             uuid.UUID
-	Name           string
-	Bio            sql.NullString
-	Username       string
-	HashedPassword string
+	UserID         uuid.UUID
+	FailedAttempts int32
 	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+type User struct {
+	ID                  uuid.UUID
+	Name                string
+	Bio                 sql.NullString
+	Username            string
+	HashedPassword      string
+	CreatedAt           time.Time
+	Email               sql.NullString
+	EmailVerifiedAt     sql.NullTime
+	TotpSecret          sql.NullString
+	TotpEnabledAt       sql.NullTime
+	TotpLastUsedStep    int64
+	Role                string
+	DeletionScheduledAt sql.NullTime
+	AvatarKey           sql.NullString
+	Reputation          int32
+}
+
+type UserBadge struct {
+	ID        int64
+	UserID    uuid.UUID
+	Badge     string
+	SourceID  uuid.NullUUID
+	SourceKey string
+	AwardedAt time.Time
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	Kind      string
+	CreatedAt time.Time
+}
+
+type UserIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    uuid.UUID
+	Email     sql.NullString
+	CreatedAt time.Time
 }
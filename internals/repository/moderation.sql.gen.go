@@ -0,0 +1,125 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: moderation.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getModerationActions = `-- name: GetModerationActions :many
+select id, actor_id, action, target_id, created_at
+from moderation_actions
+where
+    created_at <= coalesce(
+        nullif($2::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+        now()::timestamptz
+    )
+order by created_at desc
+limit $1
+`
+
+type GetModerationActionsParams struct {
+	Limit     int32
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetModerationActions(ctx context.Context, arg GetModerationActionsParams) ([]ModerationAction, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationActions, arg.Limit, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationAction
+	for rows.Next() {
+		var i ModerationAction
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorID,
+			&i.Action,
+			&i.TargetID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertModerationAction = `-- name: InsertModerationAction :exec
+insert into moderation_actions (actor_id, action, target_id)
+values ($1, $2, $3)
+`
+
+type InsertModerationActionParams struct {
+	ActorID  uuid.UUID
+	Action   string
+	TargetID uuid.UUID
+}
+
+func (q *Queries) InsertModerationAction(ctx context.Context, arg InsertModerationActionParams) error {
+	_, err := q.db.ExecContext(ctx, insertModerationAction, arg.ActorID, arg.Action, arg.TargetID)
+	return err
+}
+
+const promoteUsersToAdmin = `-- name: PromoteUsersToAdmin :execrows
+update users
+set role = 'admin'
+where id = any($1::uuid[]) and role <> 'admin'
+`
+
+func (q *Queries) PromoteUsersToAdmin(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, promoteUsersToAdmin, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reassignUserModerationActions = `-- name: ReassignUserModerationActions :exec
+update moderation_actions
+set actor_id = $1
+where actor_id = $2
+`
+
+type ReassignUserModerationActionsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignUserModerationActions(ctx context.Context, arg ReassignUserModerationActionsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignUserModerationActions, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+update users
+set role = $1
+where id = $2
+`
+
+type UpdateUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserRole, arg.Role, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
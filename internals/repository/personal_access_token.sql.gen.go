@@ -0,0 +1,165 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: personal_access_token.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const checkPersonalAccessTokenForUser = `-- name: CheckPersonalAccessTokenForUser :one
+select exists (select 1 from personal_access_tokens where id = $1 and user_id = $2 for update)
+`
+
+type CheckPersonalAccessTokenForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CheckPersonalAccessTokenForUser(ctx context.Context, arg CheckPersonalAccessTokenForUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkPersonalAccessTokenForUser, arg.ID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deletePersonalAccessToken = `-- name: DeletePersonalAccessToken :exec
+delete from personal_access_tokens where id = $1
+`
+
+func (q *Queries) DeletePersonalAccessToken(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePersonalAccessToken, id)
+	return err
+}
+
+const getPersonalAccessTokenByHash = `-- name: GetPersonalAccessTokenByHash :one
+select pat.id, pat.user_id, pat.name, pat.token_hash, pat.scopes, pat.created_at, pat.expires_at, pat.last_used_at, u.role
+from personal_access_tokens pat
+join users u on u.id = pat.user_id
+where
+    pat.token_hash = $1 and
+    (pat.expires_at is null or pat.expires_at > now())
+`
+
+type GetPersonalAccessTokenByHashRow struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	Role       string
+}
+
+func (q *Queries) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (GetPersonalAccessTokenByHashRow, error) {
+	row := q.db.QueryRowContext(ctx, getPersonalAccessTokenByHash, tokenHash)
+	var i GetPersonalAccessTokenByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.TokenHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserPersonalAccessTokens = `-- name: GetUserPersonalAccessTokens :many
+select id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at from personal_access_tokens
+where user_id = $1
+order by created_at desc
+`
+
+func (q *Queries) GetUserPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]PersonalAccessToken, error) {
+	rows, err := q.db.QueryContext(ctx, getUserPersonalAccessTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PersonalAccessToken
+	for rows.Next() {
+		var i PersonalAccessToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.TokenHash,
+			pq.Array(&i.Scopes),
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertPersonalAccessToken = `-- name: InsertPersonalAccessToken :one
+insert into personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+values ($1, $2, $3, $4, $5, $6)
+returning id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at
+`
+
+type InsertPersonalAccessTokenParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	TokenHash string
+	Scopes    []string
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) InsertPersonalAccessToken(ctx context.Context, arg InsertPersonalAccessTokenParams) (PersonalAccessToken, error) {
+	row := q.db.QueryRowContext(ctx, insertPersonalAccessToken,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.TokenHash,
+		pq.Array(arg.Scopes),
+		arg.ExpiresAt,
+	)
+	var i PersonalAccessToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.TokenHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const updatePersonalAccessTokenLastUsed = `-- name: UpdatePersonalAccessTokenLastUsed :exec
+update personal_access_tokens
+set last_used_at = now()
+where id = $1
+`
+
+func (q *Queries) UpdatePersonalAccessTokenLastUsed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, updatePersonalAccessTokenLastUsed, id)
+	return err
+}
@@ -7,12 +7,51 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+const addCommentVoteCounts = `-- name: AddCommentVoteCounts :exec
+update comments
+set
+    up_count = up_count + $1,
+    down_count = down_count + $2
+where id = $3
+`
+
+type AddCommentVoteCountsParams struct {
+	UpDelta   int32
+	DownDelta int32
+	ID        uuid.UUID
+}
+
+func (q *Queries) AddCommentVoteCounts(ctx context.Context, arg AddCommentVoteCountsParams) error {
+	_, err := q.db.ExecContext(ctx, addCommentVoteCounts, arg.UpDelta, arg.DownDelta, arg.ID)
+	return err
+}
+
+const addPostVoteCounts = `-- name: AddPostVoteCounts :exec
+update posts
+set
+    up_count = up_count + $1,
+    down_count = down_count + $2
+where id = $3
+`
+
+type AddPostVoteCountsParams struct {
+	UpDelta   int32
+	DownDelta int32
+	ID        uuid.UUID
+}
+
+func (q *Queries) AddPostVoteCounts(ctx context.Context, arg AddPostVoteCountsParams) error {
+	_, err := q.db.ExecContext(ctx, addPostVoteCounts, arg.UpDelta, arg.DownDelta, arg.ID)
+	return err
+}
+
 const checkComment = `-- name: CheckComment :one
 select exists (select 1 from comments where id = $1 for update)
 `
@@ -83,6 +122,17 @@ func (q *Queries) CheckPostForUser(ctx context.Context, arg CheckPostForUserPara
 	return exists, err
 }
 
+const checkTag = `-- name: CheckTag :one
+select exists (select 1 from tags where name = $1)
+`
+
+func (q *Queries) CheckTag(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkTag, name)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const deleteComment = `-- name: DeleteComment :exec
 delete from comments where id = $1
 `
@@ -124,6 +174,20 @@ func (q *Queries) DeletePostByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deletePostVote = `-- name: DeletePostVote :exec
+delete from post_votes where post_id = $1 and user_id = $2
+`
+
+type DeletePostVoteParams struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeletePostVote(ctx context.Context, arg DeletePostVoteParams) error {
+	_, err := q.db.ExecContext(ctx, deletePostVote, arg.PostID, arg.UserID)
+	return err
+}
+
 const deleteTagForPost = `-- name: DeleteTagForPost :exec
 delete from post_tags
 where post_id = $1 and tag_id = (select id from tags where name = $2)
@@ -139,48 +203,134 @@ func (q *Queries) DeleteTagForPost(ctx context.Context, arg DeleteTagForPostPara
 	return err
 }
 
+const getAnswerPostOwner = `-- name: GetAnswerPostOwner :one
+select p.id as post_id, p.title, u.id as user_id, u.email, u.email_verified_at
+from post_answers pa
+join posts p on p.id = pa.post_id
+join users u on u.id = p.user_id
+where pa.comment_id = $1
+`
+
+type GetAnswerPostOwnerRow struct {
+	PostID          uuid.UUID
+	Title           string
+	UserID          uuid.UUID
+	Email           sql.NullString
+	EmailVerifiedAt sql.NullTime
+}
+
+func (q *Queries) GetAnswerPostOwner(ctx context.Context, commentID uuid.UUID) (GetAnswerPostOwnerRow, error) {
+	row := q.db.QueryRowContext(ctx, getAnswerPostOwner, commentID)
+	var i GetAnswerPostOwnerRow
+	err := row.Scan(
+		&i.PostID,
+		&i.Title,
+		&i.UserID,
+		&i.Email,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getCommentAuthor = `-- name: GetCommentAuthor :one
+select user_id from comments where id = $1
+`
+
+func (q *Queries) GetCommentAuthor(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getCommentAuthor, id)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getCommentVote = `-- name: GetCommentVote :one
+select comment_id, user_id, kind from comment_votes where comment_id = $1 and user_id = $2 for update
+`
+
+type GetCommentVoteParams struct {
+	CommentID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) GetCommentVote(ctx context.Context, arg GetCommentVoteParams) (CommentVote, error) {
+	row := q.db.QueryRowContext(ctx, getCommentVote, arg.CommentID, arg.UserID)
+	var i CommentVote
+	err := row.Scan(&i.CommentID, &i.UserID, &i.Kind)
+	return i, err
+}
+
 const getCommentVoteCounts = `-- name: GetCommentVoteCounts :one
-select 
-    sum(case when kind = 'up' then 1 else 0 end) over () as up_count,
-    sum(case when kind = 'down' then 1 else 0 end) over () as down_count
-from comment_votes
-where comment_id = $1
+select up_count, down_count, score from comments where id = $1
 `
 
 type GetCommentVoteCountsRow struct {
-	UpCount   int64
-	DownCount int64
+	UpCount   int32
+	DownCount int32
+	Score     int32
 }
 
-func (q *Queries) GetCommentVoteCounts(ctx context.Context, commentID uuid.UUID) (GetCommentVoteCountsRow, error) {
-	row := q.db.QueryRowContext(ctx, getCommentVoteCounts, commentID)
+func (q *Queries) GetCommentVoteCounts(ctx context.Context, id uuid.UUID) (GetCommentVoteCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentVoteCounts, id)
 	var i GetCommentVoteCountsRow
-	err := row.Scan(&i.UpCount, &i.DownCount)
+	err := row.Scan(&i.UpCount, &i.DownCount, &i.Score)
 	return i, err
 }
 
 const getPostAnswer = `-- name: GetPostAnswer :one
-select c.id, c.post_id, c.user_id, c.content, c.created_at
+select c.id, c.post_id, c.user_id, c.content, c.created_at, c.edited_at, c.content_html, c.content_html_version, c.up_count, c.down_count, c.score, pa.accepted_at
 from post_answers pa
 join comments c on c.id = pa.comment_id
 where pa.post_id = $1
 `
 
-func (q *Queries) GetPostAnswer(ctx context.Context, postID uuid.UUID) (Comment, error) {
+type GetPostAnswerRow struct {
+	ID                 uuid.UUID
+	PostID             uuid.UUID
+	UserID             uuid.UUID
+	Content            string
+	CreatedAt          time.Time
+	EditedAt           sql.NullTime
+	ContentHtml        string
+	ContentHtmlVersion int32
+	UpCount            int32
+	DownCount          int32
+	Score              int32
+	AcceptedAt         time.Time
+}
+
+func (q *Queries) GetPostAnswer(ctx context.Context, postID uuid.UUID) (GetPostAnswerRow, error) {
 	row := q.db.QueryRowContext(ctx, getPostAnswer, postID)
-	var i Comment
+	var i GetPostAnswerRow
 	err := row.Scan(
 		&i.ID,
 		&i.PostID,
 		&i.UserID,
 		&i.Content,
 		&i.CreatedAt,
+		&i.EditedAt,
+		&i.ContentHtml,
+		&i.ContentHtmlVersion,
+		&i.UpCount,
+		&i.DownCount,
+		&i.Score,
+		&i.AcceptedAt,
 	)
 	return i, err
 }
 
+const getPostAuthor = `-- name: GetPostAuthor :one
+select user_id from posts where id = $1
+`
+
+func (q *Queries) GetPostAuthor(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getPostAuthor, id)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
 const getPostByID = `-- name: GetPostByID :one
-select id, user_id, title, content, answered, created_at from posts where id = $1
+select id, user_id, title, content, answered, created_at, content_html, content_html_version, up_count, down_count, score from posts where id = $1
 `
 
 func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
@@ -193,17 +343,53 @@ func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
 		&i.Content,
 		&i.Answered,
 		&i.CreatedAt,
+		&i.ContentHtml,
+		&i.ContentHtmlVersion,
+		&i.UpCount,
+		&i.DownCount,
+		&i.Score,
 	)
 	return i, err
 }
 
+const getPostCommentIDs = `-- name: GetPostCommentIDs :many
+select id from comments where post_id = $1
+`
+
+func (q *Queries) GetPostCommentIDs(ctx context.Context, postID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPostCommentIDs, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostComments = `-- name: GetPostComments :many
-select id, post_id, user_id, content, created_at from comments
+select id, post_id, user_id, content, created_at, edited_at, content_html, content_html_version, up_count, down_count, score from comments
 where 
     post_id = $1 and
     created_at <= coalesce(
         nullif($3::timestamptz, '0001-01-01 00:00:00'::timestamptz),
         now()::timestamptz
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = $4 and b.blocked_id = comments.user_id
     )
 order by created_at desc
 limit $2
@@ -213,10 +399,87 @@ type GetPostCommentsParams struct {
 	PostID    uuid.UUID
 	Limit     int32
 	CreatedAt time.Time
+	ViewerID  uuid.UUID
 }
 
 func (q *Queries) GetPostComments(ctx context.Context, arg GetPostCommentsParams) ([]Comment, error) {
-	rows, err := q.db.QueryContext(ctx, getPostComments, arg.PostID, arg.Limit, arg.CreatedAt)
+	rows, err := q.db.QueryContext(ctx, getPostComments,
+		arg.PostID,
+		arg.Limit,
+		arg.CreatedAt,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostCommentsByScore = `-- name: GetPostCommentsByScore :many
+select id, post_id, user_id, content, created_at, edited_at, content_html, content_html_version, up_count, down_count, score from comments
+where
+    post_id = $1 and
+    (score, created_at) <= (
+        case
+            when $3::timestamptz = '0001-01-01 00:00:00'::timestamptz then 2147483647
+            else $4::int
+        end,
+        coalesce(
+            nullif($3::timestamptz, '0001-01-01 00:00:00'::timestamptz),
+            now()::timestamptz
+        )
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = $5 and b.blocked_id = comments.user_id
+    )
+order by score desc, created_at desc
+limit $2
+`
+
+type GetPostCommentsByScoreParams struct {
+	PostID    uuid.UUID
+	Limit     int32
+	CreatedAt time.Time
+	Score     int32
+	ViewerID  uuid.UUID
+}
+
+func (q *Queries) GetPostCommentsByScore(ctx context.Context, arg GetPostCommentsByScoreParams) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, getPostCommentsByScore,
+		arg.PostID,
+		arg.Limit,
+		arg.CreatedAt,
+		arg.Score,
+		arg.ViewerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -230,6 +493,12 @@ func (q *Queries) GetPostComments(ctx context.Context, arg GetPostCommentsParams
 			&i.UserID,
 			&i.Content,
 			&i.CreatedAt,
+			&i.EditedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -274,8 +543,62 @@ func (q *Queries) GetPostTags(ctx context.Context, postID uuid.UUID) ([]string,
 	return items, nil
 }
 
+const getPostVote = `-- name: GetPostVote :one
+select post_id, user_id, kind from post_votes where post_id = $1 and user_id = $2 for update
+`
+
+type GetPostVoteParams struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetPostVote(ctx context.Context, arg GetPostVoteParams) (PostVote, error) {
+	row := q.db.QueryRowContext(ctx, getPostVote, arg.PostID, arg.UserID)
+	var i PostVote
+	err := row.Scan(&i.PostID, &i.UserID, &i.Kind)
+	return i, err
+}
+
+const getPostVotesForUser = `-- name: GetPostVotesForUser :many
+select post_id, kind from post_votes
+where user_id = $1 and post_id = any($2::uuid[])
+`
+
+type GetPostVotesForUserParams struct {
+	UserID  uuid.UUID
+	PostIds []uuid.UUID
+}
+
+type GetPostVotesForUserRow struct {
+	PostID uuid.UUID
+	Kind   string
+}
+
+func (q *Queries) GetPostVotesForUser(ctx context.Context, arg GetPostVotesForUserParams) ([]GetPostVotesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostVotesForUser, arg.UserID, pq.Array(arg.PostIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostVotesForUserRow
+	for rows.Next() {
+		var i GetPostVotesForUserRow
+		if err := rows.Scan(&i.PostID, &i.Kind); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPosts = `-- name: GetPosts :many
-select p.id, p.user_id, p.title, p.content, p.answered, p.created_at
+select p.id, p.user_id, p.title, p.content, p.answered, p.created_at, p.content_html, p.content_html_version, p.up_count, p.down_count, p.score
 from posts p
 join post_tags pt on pt.post_id = p.id
 join tags t on t.id = pt.tag_id
@@ -292,6 +615,10 @@ where
     (
         $4::varchar = '' or
         to_tsvector('english', p.title || ' ' || p.content) @@ to_tsquery($4::varchar)
+    ) and
+    not exists (
+        select 1 from user_blocks b
+        where b.blocker_id = $5 and b.blocked_id = p.user_id
     )
 order by p.created_at desc
 limit $1
@@ -302,6 +629,7 @@ type GetPostsParams struct {
 	CreatedAt time.Time
 	Tags      []string
 	Query     string
+	ViewerID  uuid.UUID
 }
 
 func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]Post, error) {
@@ -310,6 +638,7 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]Post, err
 		arg.CreatedAt,
 		pq.Array(arg.Tags),
 		arg.Query,
+		arg.ViewerID,
 	)
 	if err != nil {
 		return nil, err
@@ -325,6 +654,11 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]Post, err
 			&i.Content,
 			&i.Answered,
 			&i.CreatedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -339,8 +673,88 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]Post, err
 	return items, nil
 }
 
+const getStaleCommentsContent = `-- name: GetStaleCommentsContent :many
+select id, content from comments
+where content_html_version <> $1
+limit $2
+for update skip locked
+`
+
+type GetStaleCommentsContentParams struct {
+	ContentHtmlVersion int32
+	Limit              int32
+}
+
+type GetStaleCommentsContentRow struct {
+	ID      uuid.UUID
+	Content string
+}
+
+func (q *Queries) GetStaleCommentsContent(ctx context.Context, arg GetStaleCommentsContentParams) ([]GetStaleCommentsContentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStaleCommentsContent, arg.ContentHtmlVersion, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStaleCommentsContentRow
+	for rows.Next() {
+		var i GetStaleCommentsContentRow
+		if err := rows.Scan(&i.ID, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStalePostsContent = `-- name: GetStalePostsContent :many
+select id, content from posts
+where content_html_version <> $1
+limit $2
+for update skip locked
+`
+
+type GetStalePostsContentParams struct {
+	ContentHtmlVersion int32
+	Limit              int32
+}
+
+type GetStalePostsContentRow struct {
+	ID      uuid.UUID
+	Content string
+}
+
+func (q *Queries) GetStalePostsContent(ctx context.Context, arg GetStalePostsContentParams) ([]GetStalePostsContentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStalePostsContent, arg.ContentHtmlVersion, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStalePostsContentRow
+	for rows.Next() {
+		var i GetStalePostsContentRow
+		if err := rows.Scan(&i.ID, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserPosts = `-- name: GetUserPosts :many
-select id, user_id, title, content, answered, created_at
+select id, user_id, title, content, answered, created_at, content_html, content_html_version, up_count, down_count, score
 from posts
 where
     user_id = $1 and
@@ -374,6 +788,11 @@ func (q *Queries) GetUserPosts(ctx context.Context, arg GetUserPostsParams) ([]P
 			&i.Content,
 			&i.Answered,
 			&i.CreatedAt,
+			&i.ContentHtml,
+			&i.ContentHtmlVersion,
+			&i.UpCount,
+			&i.DownCount,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -389,15 +808,17 @@ func (q *Queries) GetUserPosts(ctx context.Context, arg GetUserPostsParams) ([]P
 }
 
 const insertComment = `-- name: InsertComment :exec
-insert into comments (id, post_id, user_id, content)
-values ($1, $2, $3, $4)
+insert into comments (id, post_id, user_id, content, content_html, content_html_version)
+values ($1, $2, $3, $4, $5, $6)
 `
 
 type InsertCommentParams struct {
-	ID      uuid.UUID
-	PostID  uuid.UUID
-	UserID  uuid.UUID
-	Content string
+	ID                 uuid.UUID
+	PostID             uuid.UUID
+	UserID             uuid.UUID
+	Content            string
+	ContentHtml        string
+	ContentHtmlVersion int32
 }
 
 func (q *Queries) InsertComment(ctx context.Context, arg InsertCommentParams) error {
@@ -406,6 +827,8 @@ func (q *Queries) InsertComment(ctx context.Context, arg InsertCommentParams) er
 		arg.PostID,
 		arg.UserID,
 		arg.Content,
+		arg.ContentHtml,
+		arg.ContentHtmlVersion,
 	)
 	return err
 }
@@ -429,16 +852,18 @@ func (q *Queries) InsertCommentVote(ctx context.Context, arg InsertCommentVotePa
 }
 
 const insertPost = `-- name: InsertPost :one
-insert into posts (id, user_id, title, content)
-values ($1, $2, $3, $4)
-returning id, user_id, title, content, answered, created_at
+insert into posts (id, user_id, title, content, content_html, content_html_version)
+values ($1, $2, $3, $4, $5, $6)
+returning id, user_id, title, content, answered, created_at, content_html, content_html_version, up_count, down_count, score
 `
 
 type InsertPostParams struct {
-	ID      uuid.UUID
-	UserID  uuid.UUID
-	Title   string
-	Content string
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	Title              string
+	Content            string
+	ContentHtml        string
+	ContentHtmlVersion int32
 }
 
 func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, error) {
@@ -447,6 +872,8 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		arg.UserID,
 		arg.Title,
 		arg.Content,
+		arg.ContentHtml,
+		arg.ContentHtmlVersion,
 	)
 	var i Post
 	err := row.Scan(
@@ -456,6 +883,11 @@ func (q *Queries) InsertPost(ctx context.Context, arg InsertPostParams) (Post, e
 		&i.Content,
 		&i.Answered,
 		&i.CreatedAt,
+		&i.ContentHtml,
+		&i.ContentHtmlVersion,
+		&i.UpCount,
+		&i.DownCount,
+		&i.Score,
 	)
 	return i, err
 }
@@ -464,7 +896,7 @@ const insertPostAnswer = `-- name: InsertPostAnswer :exec
 insert into post_answers (post_id, comment_id)
 values ($1, $2)
 on conflict (post_id) do update
-    set comment_id = excluded.comment_id
+    set comment_id = excluded.comment_id, accepted_at = now()
 `
 
 type InsertPostAnswerParams struct {
@@ -477,10 +909,28 @@ func (q *Queries) InsertPostAnswer(ctx context.Context, arg InsertPostAnswerPara
 	return err
 }
 
+const insertPostVote = `-- name: InsertPostVote :exec
+insert into post_votes (post_id, user_id, kind)
+values ($1, $2, $3)
+on conflict (post_id, user_id) do update
+    set kind = excluded.kind
+`
+
+type InsertPostVoteParams struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+	Kind   string
+}
+
+func (q *Queries) InsertPostVote(ctx context.Context, arg InsertPostVoteParams) error {
+	_, err := q.db.ExecContext(ctx, insertPostVote, arg.PostID, arg.UserID, arg.Kind)
+	return err
+}
+
 const insertTag = `-- name: InsertTag :one
 with new_tag as (
-    insert into tags (name) 
-    values ($1)
+    insert into tags (name, created_by)
+    values ($1, $2)
     on conflict (name) do nothing
     returning id
 )
@@ -489,8 +939,13 @@ union
 select id from tags where name = $1
 `
 
-func (q *Queries) InsertTag(ctx context.Context, name string) (int32, error) {
-	row := q.db.QueryRowContext(ctx, insertTag, name)
+type InsertTagParams struct {
+	Name      string
+	CreatedBy uuid.NullUUID
+}
+
+func (q *Queries) InsertTag(ctx context.Context, arg InsertTagParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, insertTag, arg.Name, arg.CreatedBy)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
@@ -514,17 +969,41 @@ func (q *Queries) InsertTagForPost(ctx context.Context, arg InsertTagForPostPara
 
 const updateComment = `-- name: UpdateComment :exec
 update comments
-set content = $1
-where id = $2
+set content = $1, content_html = $2, content_html_version = $3, edited_at = now()
+where id = $4
 `
 
 type UpdateCommentParams struct {
-	Content string
-	ID      uuid.UUID
+	Content            string
+	ContentHtml        string
+	ContentHtmlVersion int32
+	ID                 uuid.UUID
 }
 
 func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) error {
-	_, err := q.db.ExecContext(ctx, updateComment, arg.Content, arg.ID)
+	_, err := q.db.ExecContext(ctx, updateComment,
+		arg.Content,
+		arg.ContentHtml,
+		arg.ContentHtmlVersion,
+		arg.ID,
+	)
+	return err
+}
+
+const updateCommentContentHtml = `-- name: UpdateCommentContentHtml :exec
+update comments
+set content_html = $1, content_html_version = $2
+where id = $3
+`
+
+type UpdateCommentContentHtmlParams struct {
+	ContentHtml        string
+	ContentHtmlVersion int32
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdateCommentContentHtml(ctx context.Context, arg UpdateCommentContentHtmlParams) error {
+	_, err := q.db.ExecContext(ctx, updateCommentContentHtml, arg.ContentHtml, arg.ContentHtmlVersion, arg.ID)
 	return err
 }
 
@@ -532,17 +1011,44 @@ const updatePostByID = `-- name: UpdatePostByID :exec
 update posts
 set
     title = $1,
-    content = $2
-where id = $3
+    content = $2,
+    content_html = $3,
+    content_html_version = $4
+where id = $5
 `
 
 type UpdatePostByIDParams struct {
-	Title   string
-	Content string
-	ID      uuid.UUID
+	Title              string
+	Content            string
+	ContentHtml        string
+	ContentHtmlVersion int32
+	ID                 uuid.UUID
 }
 
 func (q *Queries) UpdatePostByID(ctx context.Context, arg UpdatePostByIDParams) error {
-	_, err := q.db.ExecContext(ctx, updatePostByID, arg.Title, arg.Content, arg.ID)
+	_, err := q.db.ExecContext(ctx, updatePostByID,
+		arg.Title,
+		arg.Content,
+		arg.ContentHtml,
+		arg.ContentHtmlVersion,
+		arg.ID,
+	)
+	return err
+}
+
+const updatePostContentHtml = `-- name: UpdatePostContentHtml :exec
+update posts
+set content_html = $1, content_html_version = $2
+where id = $3
+`
+
+type UpdatePostContentHtmlParams struct {
+	ContentHtml        string
+	ContentHtmlVersion int32
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdatePostContentHtml(ctx context.Context, arg UpdatePostContentHtmlParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContentHtml, arg.ContentHtml, arg.ContentHtmlVersion, arg.ID)
 	return err
 }
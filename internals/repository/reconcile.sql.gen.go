@@ -0,0 +1,137 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: reconcile.sql
+
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const lockCommentsBatch = `-- name: LockCommentsBatch :many
+select id from comments
+where id > $1
+order by id
+limit $2
+for update
+`
+
+type LockCommentsBatchParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) LockCommentsBatch(ctx context.Context, arg LockCommentsBatchParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, lockCommentsBatch, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPostsBatch = `-- name: LockPostsBatch :many
+select id from posts
+where id > $1
+order by id
+limit $2
+for update
+`
+
+type LockPostsBatchParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) LockPostsBatch(ctx context.Context, arg LockPostsBatchParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, lockPostsBatch, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reconcileCommentVoteCounts = `-- name: ReconcileCommentVoteCounts :execrows
+update comments c
+set
+    up_count = v.up_count,
+    down_count = v.down_count
+from (
+    select
+        c2.id,
+        count(cv.kind) filter (where cv.kind = 'up')::int as up_count,
+        count(cv.kind) filter (where cv.kind = 'down')::int as down_count
+    from comments c2
+    left join comment_votes cv on cv.comment_id = c2.id
+    where c2.id = any($1::uuid[])
+    group by c2.id
+) v
+where c.id = v.id and (c.up_count <> v.up_count or c.down_count <> v.down_count)
+`
+
+func (q *Queries) ReconcileCommentVoteCounts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reconcileCommentVoteCounts, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reconcilePostVoteCounts = `-- name: ReconcilePostVoteCounts :execrows
+update posts p
+set
+    up_count = v.up_count,
+    down_count = v.down_count
+from (
+    select
+        p2.id,
+        count(pv.kind) filter (where pv.kind = 'up')::int as up_count,
+        count(pv.kind) filter (where pv.kind = 'down')::int as down_count
+    from posts p2
+    left join post_votes pv on pv.post_id = p2.id
+    where p2.id = any($1::uuid[])
+    group by p2.id
+) v
+where p.id = v.id and (p.up_count <> v.up_count or p.down_count <> v.down_count)
+`
+
+func (q *Queries) ReconcilePostVoteCounts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reconcilePostVoteCounts, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,254 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: reputation.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addUserReputation = `-- name: AddUserReputation :exec
+update users
+set reputation = reputation + $1
+where id = $2
+`
+
+type AddUserReputationParams struct {
+	Reputation int32
+	ID         uuid.UUID
+}
+
+func (q *Queries) AddUserReputation(ctx context.Context, arg AddUserReputationParams) error {
+	_, err := q.db.ExecContext(ctx, addUserReputation, arg.Reputation, arg.ID)
+	return err
+}
+
+const getActiveReputationEvent = `-- name: GetActiveReputationEvent :one
+select e.id, e.user_id, e.actor_id, e.kind, e.source_id, e.points, e.reverses_id, e.created_at
+from reputation_events e
+where
+    e.kind = $1 and
+    e.source_id = $2 and
+    e.actor_id = $3 and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id desc
+limit 1
+`
+
+type GetActiveReputationEventParams struct {
+	Kind     string
+	SourceID uuid.UUID
+	ActorID  uuid.NullUUID
+}
+
+func (q *Queries) GetActiveReputationEvent(ctx context.Context, arg GetActiveReputationEventParams) (ReputationEvent, error) {
+	row := q.db.QueryRowContext(ctx, getActiveReputationEvent, arg.Kind, arg.SourceID, arg.ActorID)
+	var i ReputationEvent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ActorID,
+		&i.Kind,
+		&i.SourceID,
+		&i.Points,
+		&i.ReversesID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getActiveReputationEventsByActor = `-- name: GetActiveReputationEventsByActor :many
+select e.id, e.user_id, e.actor_id, e.kind, e.source_id, e.points, e.reverses_id, e.created_at
+from reputation_events e
+where
+    e.actor_id = $1 and
+    e.kind = any($2::varchar[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id
+`
+
+type GetActiveReputationEventsByActorParams struct {
+	ActorID uuid.NullUUID
+	Kinds   []string
+}
+
+func (q *Queries) GetActiveReputationEventsByActor(ctx context.Context, arg GetActiveReputationEventsByActorParams) ([]ReputationEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveReputationEventsByActor, arg.ActorID, pq.Array(arg.Kinds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReputationEvent
+	for rows.Next() {
+		var i ReputationEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Kind,
+			&i.SourceID,
+			&i.Points,
+			&i.ReversesID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveReputationEventsBySources = `-- name: GetActiveReputationEventsBySources :many
+select e.id, e.user_id, e.actor_id, e.kind, e.source_id, e.points, e.reverses_id, e.created_at
+from reputation_events e
+where
+    e.source_id = any($1::uuid[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id)
+order by e.id
+`
+
+func (q *Queries) GetActiveReputationEventsBySources(ctx context.Context, sourceIds []uuid.UUID) ([]ReputationEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveReputationEventsBySources, pq.Array(sourceIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReputationEvent
+	for rows.Next() {
+		var i ReputationEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Kind,
+			&i.SourceID,
+			&i.Points,
+			&i.ReversesID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserReputationEvents = `-- name: GetUserReputationEvents :many
+select id, user_id, actor_id, kind, source_id, points, reverses_id, created_at
+from reputation_events
+where
+    user_id = $1 and
+    id <= coalesce(nullif($3::bigint, 0), 9223372036854775807)
+order by id desc
+limit $2
+`
+
+type GetUserReputationEventsParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	ID     int64
+}
+
+func (q *Queries) GetUserReputationEvents(ctx context.Context, arg GetUserReputationEventsParams) ([]ReputationEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getUserReputationEvents, arg.UserID, arg.Limit, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReputationEvent
+	for rows.Next() {
+		var i ReputationEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Kind,
+			&i.SourceID,
+			&i.Points,
+			&i.ReversesID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserReputationSince = `-- name: GetUserReputationSince :one
+select coalesce(sum(e.points), 0)::int
+from reputation_events e
+where
+    e.user_id = $1 and
+    e.kind = any($2::varchar[]) and
+    e.reverses_id is null and
+    not exists (select 1 from reputation_events r where r.reverses_id = e.id) and
+    e.created_at >= $3
+`
+
+type GetUserReputationSinceParams struct {
+	UserID uuid.UUID
+	Kinds  []string
+	Since  time.Time
+}
+
+func (q *Queries) GetUserReputationSince(ctx context.Context, arg GetUserReputationSinceParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserReputationSince, arg.UserID, pq.Array(arg.Kinds), arg.Since)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const insertReputationEvent = `-- name: InsertReputationEvent :exec
+insert into reputation_events (user_id, actor_id, kind, source_id, points, reverses_id)
+values ($1, $2, $3, $4, $5, $6)
+`
+
+type InsertReputationEventParams struct {
+	UserID     uuid.UUID
+	ActorID    uuid.NullUUID
+	Kind       string
+	SourceID   uuid.UUID
+	Points     int32
+	ReversesID sql.NullInt64
+}
+
+func (q *Queries) InsertReputationEvent(ctx context.Context, arg InsertReputationEventParams) error {
+	_, err := q.db.ExecContext(ctx, insertReputationEvent,
+		arg.UserID,
+		arg.ActorID,
+		arg.Kind,
+		arg.SourceID,
+		arg.Points,
+		arg.ReversesID,
+	)
+	return err
+}
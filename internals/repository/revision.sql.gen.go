@@ -0,0 +1,224 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: revision.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const getCommentRevisions = `-- name: GetCommentRevisions :many
+select comment_id, revision, user_id, content, created_at
+from comment_revisions
+where
+    comment_id = $1 and
+    revision <= coalesce(nullif($3::int, 0), 2147483647)
+order by revision desc
+limit $2
+`
+
+type GetCommentRevisionsParams struct {
+	CommentID uuid.UUID
+	Limit     int32
+	Revision  int32
+}
+
+func (q *Queries) GetCommentRevisions(ctx context.Context, arg GetCommentRevisionsParams) ([]CommentRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentRevisions, arg.CommentID, arg.Limit, arg.Revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CommentRevision
+	for rows.Next() {
+		var i CommentRevision
+		if err := rows.Scan(
+			&i.CommentID,
+			&i.Revision,
+			&i.UserID,
+			&i.Content,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostRevision = `-- name: GetPostRevision :one
+select post_id, revision, user_id, title, content, edit_summary, created_at from post_revisions where post_id = $1 and revision = $2
+`
+
+type GetPostRevisionParams struct {
+	PostID   uuid.UUID
+	Revision int32
+}
+
+func (q *Queries) GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, getPostRevision, arg.PostID, arg.Revision)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Revision,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.EditSummary,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPostRevisions = `-- name: GetPostRevisions :many
+select post_id, revision, user_id, title, content, edit_summary, created_at
+from post_revisions
+where
+    post_id = $1 and
+    revision <= coalesce(nullif($3::int, 0), 2147483647)
+order by revision desc
+limit $2
+`
+
+type GetPostRevisionsParams struct {
+	PostID   uuid.UUID
+	Limit    int32
+	Revision int32
+}
+
+func (q *Queries) GetPostRevisions(ctx context.Context, arg GetPostRevisionsParams) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getPostRevisions, arg.PostID, arg.Limit, arg.Revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRevision
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Revision,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.EditSummary,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertCommentRevision = `-- name: InsertCommentRevision :exec
+insert into comment_revisions (comment_id, revision, user_id, content)
+values (
+    $1,
+    (select coalesce(max(revision), 0) + 1 from comment_revisions where comment_id = $1),
+    $2,
+    $3
+)
+`
+
+type InsertCommentRevisionParams struct {
+	CommentID uuid.UUID
+	UserID    uuid.UUID
+	Content   string
+}
+
+func (q *Queries) InsertCommentRevision(ctx context.Context, arg InsertCommentRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, insertCommentRevision, arg.CommentID, arg.UserID, arg.Content)
+	return err
+}
+
+const insertPostRevision = `-- name: InsertPostRevision :one
+insert into post_revisions (post_id, revision, user_id, title, content, edit_summary)
+values (
+    $1,
+    (select coalesce(max(revision), 0) + 1 from post_revisions where post_id = $1),
+    $2,
+    $3,
+    $4,
+    $5
+)
+returning post_id, revision, user_id, title, content, edit_summary, created_at
+`
+
+type InsertPostRevisionParams struct {
+	PostID      uuid.UUID
+	UserID      uuid.UUID
+	Title       string
+	Content     string
+	EditSummary sql.NullString
+}
+
+func (q *Queries) InsertPostRevision(ctx context.Context, arg InsertPostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, insertPostRevision,
+		arg.PostID,
+		arg.UserID,
+		arg.Title,
+		arg.Content,
+		arg.EditSummary,
+	)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Revision,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.EditSummary,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const reassignUserCommentRevisions = `-- name: ReassignUserCommentRevisions :exec
+update comment_revisions
+set user_id = $1
+where user_id = $2
+`
+
+type ReassignUserCommentRevisionsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignUserCommentRevisions(ctx context.Context, arg ReassignUserCommentRevisionsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignUserCommentRevisions, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const reassignUserPostRevisions = `-- name: ReassignUserPostRevisions :exec
+update post_revisions
+set user_id = $1
+where user_id = $2
+`
+
+type ReassignUserPostRevisionsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignUserPostRevisions(ctx context.Context, arg ReassignUserPostRevisionsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignUserPostRevisions, arg.ToUserID, arg.FromUserID)
+	return err
+}
@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: session.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkSessionForUser = `-- name: CheckSessionForUser :one
+select exists (select 1 from sessions where id = $1 and user_id = $2 for update)
+`
+
+type CheckSessionForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CheckSessionForUser(ctx context.Context, arg CheckSessionForUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkSessionForUser, arg.ID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const deleteSession = `-- name: DeleteSession :exec
+delete from sessions where id = $1
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteSession, id)
+	return err
+}
+
+const deleteUserSessions = `-- name: DeleteUserSessions :exec
+delete from sessions where user_id = $1
+`
+
+func (q *Queries) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSessions, userID)
+	return err
+}
+
+const deleteUserSessionsExcept = `-- name: DeleteUserSessionsExcept :exec
+delete from sessions where user_id = $1 and id != $2
+`
+
+type DeleteUserSessionsExceptParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) DeleteUserSessionsExcept(ctx context.Context, arg DeleteUserSessionsExceptParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSessionsExcept, arg.UserID, arg.ID)
+	return err
+}
+
+const getUserSessions = `-- name: GetUserSessions :many
+select
+    s.id,
+    s.user_agent,
+    s.ip_address,
+    s.created_at,
+    rt.created_at as refreshed_at,
+    rt.expires_at
+from sessions s
+join refresh_tokens rt on rt.family_id = s.id
+where
+    s.user_id = $1 and
+    rt.used_at is null and
+    rt.expires_at > now()
+order by s.created_at desc
+`
+
+type GetUserSessionsRow struct {
+	ID          uuid.UUID
+	UserAgent   string
+	IpAddress   string
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+	ExpiresAt   time.Time
+}
+
+func (q *Queries) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]GetUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserSessionsRow
+	for rows.Next() {
+		var i GetUserSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.RefreshedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertSession = `-- name: InsertSession :exec
+insert into sessions (id, user_id, user_agent, ip_address)
+values ($1, $2, $3, $4)
+`
+
+type InsertSessionParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	UserAgent string
+	IpAddress string
+}
+
+func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) error {
+	_, err := q.db.ExecContext(ctx, insertSession,
+		arg.ID,
+		arg.UserID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
+	return err
+}
@@ -7,39 +7,126 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const deleteExpiredActionTokens = `-- name: DeleteExpiredActionTokens :exec
+delete from action_tokens where expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredActionTokens(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredActionTokens)
+	return err
+}
+
 const getRefreshToken = `-- name: GetRefreshToken :one
-select token, user_id, created_at, expires_at from refresh_tokens where token = $1
+select token_hash, user_id, created_at, expires_at, family_id, parent_token_hash, used_at from refresh_tokens where token_hash = $1 for update
 `
 
-func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshToken, token)
+func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.UserID,
 		&i.CreatedAt,
 		&i.ExpiresAt,
+		&i.FamilyID,
+		&i.ParentTokenHash,
+		&i.UsedAt,
 	)
 	return i, err
 }
 
-const insertRefreshToken = `-- name: InsertRefreshToken :exec
-insert into refresh_tokens (token, user_id, expires_at)
-values ($1, $2, $3)
+const insertActionToken = `-- name: InsertActionToken :exec
+insert into action_tokens (id, user_id, purpose, email, expires_at)
+values ($1, $2, $3, $4, $5)
 `
 
-type InsertRefreshTokenParams struct {
-	Token     string
+type InsertActionTokenParams struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Purpose   string
+	Email     string
 	ExpiresAt time.Time
 }
 
+func (q *Queries) InsertActionToken(ctx context.Context, arg InsertActionTokenParams) error {
+	_, err := q.db.ExecContext(ctx, insertActionToken,
+		arg.ID,
+		arg.UserID,
+		arg.Purpose,
+		arg.Email,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const insertRefreshToken = `-- name: InsertRefreshToken :exec
+insert into refresh_tokens (token_hash, user_id, family_id, parent_token_hash, expires_at)
+values ($1, $2, $3, $4, $5)
+`
+
+type InsertRefreshTokenParams struct {
+	TokenHash       string
+	UserID          uuid.UUID
+	FamilyID        uuid.UUID
+	ParentTokenHash sql.NullString
+	ExpiresAt       time.Time
+}
+
 func (q *Queries) InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, insertRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, insertRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.FamilyID,
+		arg.ParentTokenHash,
+		arg.ExpiresAt,
+	)
 	return err
 }
+
+const markRefreshTokenAsUsed = `-- name: MarkRefreshTokenAsUsed :exec
+update refresh_tokens
+set used_at = now()
+where token_hash = $1
+`
+
+func (q *Queries) MarkRefreshTokenAsUsed(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, markRefreshTokenAsUsed, tokenHash)
+	return err
+}
+
+const useActionToken = `-- name: UseActionToken :one
+update action_tokens
+set used_at = now()
+where
+    id = $1 and
+    purpose = $2 and
+    used_at is null and
+    expires_at > now()
+returning id, user_id, purpose, email, created_at, expires_at, used_at
+`
+
+type UseActionTokenParams struct {
+	ID      uuid.UUID
+	Purpose string
+}
+
+func (q *Queries) UseActionToken(ctx context.Context, arg UseActionTokenParams) (ActionToken, error) {
+	row := q.db.QueryRowContext(ctx, useActionToken, arg.ID, arg.Purpose)
+	var i ActionToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Purpose,
+		&i.Email,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,185 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: two_factor.sql
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteExpiredTwoFactorChallenges = `-- name: DeleteExpiredTwoFactorChallenges :exec
+delete from two_factor_challenges where expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredTwoFactorChallenges(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredTwoFactorChallenges)
+	return err
+}
+
+const deleteTwoFactorChallenge = `-- name: DeleteTwoFactorChallenge :exec
+delete from two_factor_challenges where id = $1
+`
+
+func (q *Queries) DeleteTwoFactorChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteTwoFactorChallenge, id)
+	return err
+}
+
+const deleteUserRecoveryCodes = `-- name: DeleteUserRecoveryCodes :exec
+delete from recovery_codes where user_id = $1
+`
+
+func (q *Queries) DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserRecoveryCodes, userID)
+	return err
+}
+
+const disableUserTotp = `-- name: DisableUserTotp :exec
+update users
+set
+    totp_secret = null,
+    totp_enabled_at = null,
+    totp_last_used_step = 0
+where id = $1
+`
+
+func (q *Queries) DisableUserTotp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableUserTotp, id)
+	return err
+}
+
+const enableUserTotp = `-- name: EnableUserTotp :exec
+update users
+set
+    totp_enabled_at = now(),
+    totp_last_used_step = $1
+where id = $2
+`
+
+type EnableUserTotpParams struct {
+	TotpLastUsedStep int64
+	ID               uuid.UUID
+}
+
+func (q *Queries) EnableUserTotp(ctx context.Context, arg EnableUserTotpParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTotp, arg.TotpLastUsedStep, arg.ID)
+	return err
+}
+
+const getTwoFactorChallenge = `-- name: GetTwoFactorChallenge :one
+select id, user_id, failed_attempts, created_at, expires_at from two_factor_challenges where id = $1 and expires_at > now() for update
+`
+
+func (q *Queries) GetTwoFactorChallenge(ctx context.Context, id uuid.UUID) (TwoFactorChallenge, error) {
+	row := q.db.QueryRowContext(ctx, getTwoFactorChallenge, id)
+	var i TwoFactorChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FailedAttempts,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const incrementTwoFactorChallengeFailedAttempts = `-- name: IncrementTwoFactorChallengeFailedAttempts :exec
+update two_factor_challenges
+set failed_attempts = failed_attempts + 1
+where id = $1
+`
+
+func (q *Queries) IncrementTwoFactorChallengeFailedAttempts(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, incrementTwoFactorChallengeFailedAttempts, id)
+	return err
+}
+
+const insertRecoveryCode = `-- name: InsertRecoveryCode :exec
+insert into recovery_codes (user_id, code_hash)
+values ($1, $2)
+`
+
+type InsertRecoveryCodeParams struct {
+	UserID   uuid.UUID
+	CodeHash string
+}
+
+func (q *Queries) InsertRecoveryCode(ctx context.Context, arg InsertRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, insertRecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const insertTwoFactorChallenge = `-- name: InsertTwoFactorChallenge :exec
+insert into two_factor_challenges (id, user_id, expires_at)
+values ($1, $2, $3)
+`
+
+type InsertTwoFactorChallengeParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) InsertTwoFactorChallenge(ctx context.Context, arg InsertTwoFactorChallengeParams) error {
+	_, err := q.db.ExecContext(ctx, insertTwoFactorChallenge, arg.ID, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const setUserTotpSecret = `-- name: SetUserTotpSecret :exec
+update users
+set
+    totp_secret = $1,
+    totp_enabled_at = null
+where id = $2
+`
+
+type SetUserTotpSecretParams struct {
+	TotpSecret sql.NullString
+	ID         uuid.UUID
+}
+
+func (q *Queries) SetUserTotpSecret(ctx context.Context, arg SetUserTotpSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTotpSecret, arg.TotpSecret, arg.ID)
+	return err
+}
+
+const updateUserTotpLastUsedStep = `-- name: UpdateUserTotpLastUsedStep :exec
+update users
+set totp_last_used_step = $1
+where id = $2
+`
+
+type UpdateUserTotpLastUsedStepParams struct {
+	TotpLastUsedStep int64
+	ID               uuid.UUID
+}
+
+func (q *Queries) UpdateUserTotpLastUsedStep(ctx context.Context, arg UpdateUserTotpLastUsedStepParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserTotpLastUsedStep, arg.TotpLastUsedStep, arg.ID)
+	return err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+update recovery_codes
+set used_at = now()
+where user_id = $1 and code_hash = $2 and used_at is null
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   uuid.UUID
+	CodeHash string
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
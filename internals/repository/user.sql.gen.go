@@ -8,10 +8,25 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+update users
+set deletion_scheduled_at = null
+where id = $1 and deletion_scheduled_at is not null
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const checkUserID = `-- name: CheckUserID :one
 select exists (select 1 from users where id = $1 for update)
 `
@@ -50,6 +65,22 @@ func (q *Queries) CheckUsernameExceptUserID(ctx context.Context, arg CheckUserna
 	return exists, err
 }
 
+const checkVerifiedEmailExceptUserID = `-- name: CheckVerifiedEmailExceptUserID :one
+select exists (select 1 from users where email = $1 and email_verified_at is not null and id != $2 for update)
+`
+
+type CheckVerifiedEmailExceptUserIDParams struct {
+	Email sql.NullString
+	ID    uuid.UUID
+}
+
+func (q *Queries) CheckVerifiedEmailExceptUserID(ctx context.Context, arg CheckVerifiedEmailExceptUserIDParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, checkVerifiedEmailExceptUserID, arg.Email, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const deleteUserById = `-- name: DeleteUserById :exec
 delete from users where id = $1
 `
@@ -59,8 +90,102 @@ func (q *Queries) DeleteUserById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteUserCommentVotes = `-- name: DeleteUserCommentVotes :exec
+with deleted_votes as (
+    delete from comment_votes where comment_votes.user_id = $1
+    returning comment_id, kind
+)
+update comments c
+set
+    up_count = c.up_count - v.up_count,
+    down_count = c.down_count - v.down_count
+from (
+    select
+        comment_id,
+        count(*) filter (where kind = 'up')::int as up_count,
+        count(*) filter (where kind = 'down')::int as down_count
+    from deleted_votes
+    group by comment_id
+) v
+where c.id = v.comment_id
+`
+
+func (q *Queries) DeleteUserCommentVotes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserCommentVotes, userID)
+	return err
+}
+
+const deleteUserPostVotes = `-- name: DeleteUserPostVotes :exec
+with deleted_votes as (
+    delete from post_votes where post_votes.user_id = $1
+    returning post_id, kind
+)
+update posts p
+set
+    up_count = p.up_count - v.up_count,
+    down_count = p.down_count - v.down_count
+from (
+    select
+        post_id,
+        count(*) filter (where kind = 'up')::int as up_count,
+        count(*) filter (where kind = 'down')::int as down_count
+    from deleted_votes
+    group by post_id
+) v
+where p.id = v.post_id
+`
+
+func (q *Queries) DeleteUserPostVotes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPostVotes, userID)
+	return err
+}
+
+const getUserActivityStats = `-- name: GetUserActivityStats :one
+select
+    u.created_at,
+    (
+        select count(*)
+        from post_answers pa
+        join comments c on c.id = pa.comment_id
+        join posts p on p.id = pa.post_id
+        where c.user_id = u.id and p.user_id != u.id
+    )::int as accepted_answers,
+    (
+        select coalesce(sum(case when cv.kind = 'up' then 1 else -1 end), 0)
+        from comment_votes cv
+        join comments c on c.id = cv.comment_id
+        where c.user_id = u.id and cv.user_id != u.id
+    )::int as net_upvotes
+from users u
+where u.id = $1
+`
+
+type GetUserActivityStatsRow struct {
+	CreatedAt       time.Time
+	AcceptedAnswers int32
+	NetUpvotes      int32
+}
+
+func (q *Queries) GetUserActivityStats(ctx context.Context, id uuid.UUID) (GetUserActivityStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserActivityStats, id)
+	var i GetUserActivityStatsRow
+	err := row.Scan(&i.CreatedAt, &i.AcceptedAnswers, &i.NetUpvotes)
+	return i, err
+}
+
+const getUserAvatarKey = `-- name: GetUserAvatarKey :one
+select avatar_key from users where id = $1 for update
+`
+
+func (q *Queries) GetUserAvatarKey(ctx context.Context, id uuid.UUID) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, getUserAvatarKey, id)
+	var avatar_key sql.NullString
+	err := row.Scan(&avatar_key)
+	return avatar_key, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
-select id, name, bio, username, hashed_password, created_at from users where id = $1
+select id, name, bio, username, hashed_password, created_at, email, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, role, deletion_scheduled_at, avatar_key, reputation from users where id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -73,12 +198,21 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Username,
 		&i.HashedPassword,
 		&i.CreatedAt,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.Role,
+		&i.DeletionScheduledAt,
+		&i.AvatarKey,
+		&i.Reputation,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-select id, name, bio, username, hashed_password, created_at from users where username = $1
+select id, name, bio, username, hashed_password, created_at, email, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, role, deletion_scheduled_at, avatar_key, reputation from users where username = $1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
@@ -91,10 +225,84 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Username,
 		&i.HashedPassword,
 		&i.CreatedAt,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.Role,
+		&i.DeletionScheduledAt,
+		&i.AvatarKey,
+		&i.Reputation,
 	)
 	return i, err
 }
 
+const getUserByVerifiedEmail = `-- name: GetUserByVerifiedEmail :one
+select id, name, bio, username, hashed_password, created_at, email, email_verified_at, totp_secret, totp_enabled_at, totp_last_used_step, role, deletion_scheduled_at, avatar_key, reputation from users where email = $1 and email_verified_at is not null
+`
+
+func (q *Queries) GetUserByVerifiedEmail(ctx context.Context, email sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByVerifiedEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Bio,
+		&i.Username,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastUsedStep,
+		&i.Role,
+		&i.DeletionScheduledAt,
+		&i.AvatarKey,
+		&i.Reputation,
+	)
+	return i, err
+}
+
+const getUsersDueForDeletion = `-- name: GetUsersDueForDeletion :many
+select id, username, avatar_key
+from users
+where deletion_scheduled_at <= now()
+order by deletion_scheduled_at
+limit $1
+for update skip locked
+`
+
+type GetUsersDueForDeletionRow struct {
+	ID        uuid.UUID
+	Username  string
+	AvatarKey sql.NullString
+}
+
+func (q *Queries) GetUsersDueForDeletion(ctx context.Context, limit int32) ([]GetUsersDueForDeletionRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersDueForDeletion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersDueForDeletionRow
+	for rows.Next() {
+		var i GetUsersDueForDeletionRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.AvatarKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const insertUser = `-- name: InsertUser :execrows
 insert into users (id, name, bio, username, hashed_password)
 values ($1, $2, $3, $4, $5)
@@ -123,31 +331,153 @@ func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) (int64,
 	return result.RowsAffected()
 }
 
+const reassignUserComments = `-- name: ReassignUserComments :exec
+update comments
+set user_id = $1
+where user_id = $2
+`
+
+type ReassignUserCommentsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignUserComments(ctx context.Context, arg ReassignUserCommentsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignUserComments, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const reassignUserPosts = `-- name: ReassignUserPosts :exec
+update posts
+set user_id = $1
+where user_id = $2
+`
+
+type ReassignUserPostsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignUserPosts(ctx context.Context, arg ReassignUserPostsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignUserPosts, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+update users
+set deletion_scheduled_at = coalesce(deletion_scheduled_at, $1)
+where id = $2
+returning deletion_scheduled_at
+`
+
+type ScheduleUserDeletionParams struct {
+	DeletionScheduledAt sql.NullTime
+	ID                  uuid.UUID
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, scheduleUserDeletion, arg.DeletionScheduledAt, arg.ID)
+	var deletion_scheduled_at sql.NullTime
+	err := row.Scan(&deletion_scheduled_at)
+	return deletion_scheduled_at, err
+}
+
+const setUserEmailAsVerified = `-- name: SetUserEmailAsVerified :execrows
+update users
+set email_verified_at = now()
+where
+    users.id = $1 and
+    users.email = $2 and
+    not exists (select 1 from users u where u.email = $2 and u.email_verified_at is not null and u.id != $1)
+`
+
+type SetUserEmailAsVerifiedParams struct {
+	ID    uuid.UUID
+	Email sql.NullString
+}
+
+func (q *Queries) SetUserEmailAsVerified(ctx context.Context, arg SetUserEmailAsVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserEmailAsVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserAvatarKey = `-- name: UpdateUserAvatarKey :exec
+update users
+set avatar_key = $1
+where id = $2
+`
+
+type UpdateUserAvatarKeyParams struct {
+	AvatarKey sql.NullString
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateUserAvatarKey(ctx context.Context, arg UpdateUserAvatarKeyParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserAvatarKey, arg.AvatarKey, arg.ID)
+	return err
+}
+
 const updateUserByID = `-- name: UpdateUserByID :exec
 update users
 set
-    name = $1,
-    bio = $2,
-    username = $3,
-    hashed_password = $4
+    name = coalesce($1, name),
+    bio = case when $2::bool then $3 else bio end,
+    username = coalesce($4, username)
 where id = $5
 `
 
 type UpdateUserByIDParams struct {
-	Name           string
-	Bio            sql.NullString
-	Username       string
-	HashedPassword string
-	ID             uuid.UUID
+	Name     sql.NullString
+	SetBio   bool
+	Bio      sql.NullString
+	Username sql.NullString
+	ID       uuid.UUID
 }
 
 func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams) error {
 	_, err := q.db.ExecContext(ctx, updateUserByID,
 		arg.Name,
+		arg.SetBio,
 		arg.Bio,
 		arg.Username,
-		arg.HashedPassword,
 		arg.ID,
 	)
 	return err
 }
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+update users
+set
+    email = $1,
+    email_verified_at = null
+where id = $2
+`
+
+type UpdateUserEmailParams struct {
+	Email sql.NullString
+	ID    uuid.UUID
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.Email, arg.ID)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+update users
+set hashed_password = $1
+where id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
+	return err
+}
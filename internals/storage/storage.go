@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"log"
+	"os"
+)
+
+// Storage keeps public files, like avatars, under slash separated keys.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can fetch the file of the key.
+	URL(key string) string
+}
+
+var Default Storage
+
+func init() {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "local", "":
+		Default = &LocalStorage{
+			Dir:     cmp.Or(os.Getenv("STORAGE_DIR"), "data/storage"),
+			BaseURL: cmp.Or(os.Getenv("STORAGE_URL"), "/media"),
+		}
+	default:
+		log.Fatal("unknown storage backend: ", os.Getenv("STORAGE_BACKEND"))
+	}
+}
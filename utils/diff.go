@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// Limits on the work done by a diff, the texts come from user content so they can be large and completely
+// different. When one is exceeded, the changed part is reported as a single delete and insert.
+const (
+	// maxDiffBytes bounds the tokenizing, texts larger than it aren't diffed at all.
+	maxDiffBytes = 1 << 20
+	// maxDiffEdits bounds the memory, which grows with the square of the edit distance.
+	maxDiffEdits = 1000
+	// maxDiffTokens bounds the time, which grows with the number of tokens times the edit distance.
+	maxDiffTokens = 50000
+)
+
+// DiffOp is a run of consecutive tokens that are kept, inserted or deleted going from the old text to the new one.
+type DiffOp struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+var wordTokenRegex = regexp.MustCompile(`\s+|[^\s]+`)
+
+// DiffLines diffs old and new line by line, every line keeps its trailing newline.
+func DiffLines(old, new string) []DiffOp {
+	if len(old)+len(new) > maxDiffBytes {
+		return replaceDiff(old, new)
+	}
+	return diffTokens(strings.SplitAfter(old, "\n"), strings.SplitAfter(new, "\n"))
+}
+
+// DiffWords diffs old and new word by word, the whitespace between words is a token too.
+func DiffWords(old, new string) []DiffOp {
+	if len(old)+len(new) > maxDiffBytes {
+		return replaceDiff(old, new)
+	}
+	return diffTokens(wordTokenRegex.FindAllString(old, -1), wordTokenRegex.FindAllString(new, -1))
+}
+
+func diffTokens(a, b []string) []DiffOp {
+	// NOTE: edits are usually local, so skip the common prefix and suffix before running the diff on what's left.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	ops := []DiffOp{}
+	ops = appendDiffOp(ops, DiffEqual, strings.Join(a[:prefix], ""))
+	if middle, ok := myersDiff(middleA, middleB); ok {
+		for _, op := range middle {
+			ops = appendDiffOp(ops, op.Kind, op.Text)
+		}
+	} else {
+		ops = appendDiffOp(ops, DiffDelete, strings.Join(middleA, ""))
+		ops = appendDiffOp(ops, DiffInsert, strings.Join(middleB, ""))
+	}
+	ops = appendDiffOp(ops, DiffEqual, strings.Join(a[len(a)-suffix:], ""))
+	return ops
+}
+
+// replaceDiff is the diff of texts too large to compare, old is deleted and new is inserted as a whole.
+func replaceDiff(old, new string) []DiffOp {
+	if old == new {
+		return appendDiffOp([]DiffOp{}, DiffEqual, old)
+	}
+	return appendDiffOp(appendDiffOp([]DiffOp{}, DiffDelete, old), DiffInsert, new)
+}
+
+// appendDiffOp merges text into the last op when it's of the same kind, so that clients get runs instead of single tokens.
+func appendDiffOp(ops []DiffOp, kind, text string) []DiffOp {
+	if text == "" {
+		return ops
+	}
+	if len(ops) > 0 && ops[len(ops)-1].Kind == kind {
+		ops[len(ops)-1].Text += text
+		return ops
+	}
+	return append(ops, DiffOp{Kind: kind, Text: text})
+}
+
+// myersDiff finds the shortest edit script between a and b with Myers' algorithm, which runs in
+// O((n+m)d) time for d differences. It gives up when the texts exceed the diff limits.
+func myersDiff(a, b []string) ([]DiffOp, bool) {
+	n, m := len(a), len(b)
+	if n+m > maxDiffTokens {
+		return nil, false
+	}
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		// NOTE: only the diagonals reachable in d steps matter, so keep just those to bound the memory to O(d^2).
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace, d), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrackDiff walks the trace back from the end and returns the edit script, one op per token.
+func backtrackDiff(a, b []string, trace [][]int, d int) []DiffOp {
+	var reversed []DiffOp
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		// NOTE: trace[d] starts at diagonal -d-1.
+		offset := d + 1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, DiffOp{Kind: DiffEqual, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, DiffOp{Kind: DiffInsert, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, DiffOp{Kind: DiffDelete, Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, DiffOp{Kind: DiffEqual, Text: a[x]})
+	}
+
+	ops := make([]DiffOp, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		ops = append(ops, reversed[i])
+	}
+	return ops
+}
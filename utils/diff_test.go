@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// applyDiff rebuilds the old and new texts from the ops.
+func applyDiff(ops []DiffOp) (string, string) {
+	var old, new strings.Builder
+	for _, op := range ops {
+		if op.Kind != DiffInsert {
+			old.WriteString(op.Text)
+		}
+		if op.Kind != DiffDelete {
+			new.WriteString(op.Text)
+		}
+	}
+	return old.String(), new.String()
+}
+
+func TestDiff(t *testing.T) {
+	// NOTE: fully different texts with more tokens than the edit limit, the diff must give up instead of growing.
+	var bigOld, bigNew strings.Builder
+	for i := range maxDiffEdits {
+		bigOld.WriteString("a" + strings.Repeat("x", i%7) + "\n")
+		bigNew.WriteString("b" + strings.Repeat("y", i%5) + "\n")
+	}
+
+	tests := []struct {
+		name string
+		diff func(old, new string) []DiffOp
+		old  string
+		new  string
+		want []DiffOp
+	}{
+		{
+			name: "empty lines",
+			diff: DiffLines,
+			want: []DiffOp{},
+		},
+		{
+			name: "empty words",
+			diff: DiffWords,
+			want: []DiffOp{},
+		},
+		{
+			name: "equal lines",
+			diff: DiffLines,
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: []DiffOp{{DiffEqual, "a\nb\n"}},
+		},
+		{
+			name: "changed line",
+			diff: DiffLines,
+			old:  "a\nb\nc\n",
+			new:  "a\nx\nc\n",
+			want: []DiffOp{{DiffEqual, "a\n"}, {DiffDelete, "b\n"}, {DiffInsert, "x\n"}, {DiffEqual, "c\n"}},
+		},
+		{
+			name: "moved line",
+			diff: DiffLines,
+			old:  "a\nb\nc\n",
+			new:  "a\nc\nb\n",
+		},
+		{
+			name: "changed words",
+			diff: DiffWords,
+			old:  "the quick brown fox",
+			new:  "the slow brown dog jumps",
+			want: []DiffOp{
+				{DiffEqual, "the "},
+				{DiffDelete, "quick"},
+				{DiffInsert, "slow"},
+				{DiffEqual, " brown "},
+				{DiffDelete, "fox"},
+				{DiffInsert, "dog jumps"},
+			},
+		},
+		{
+			name: "insert only",
+			diff: DiffWords,
+			new:  "hello world",
+			want: []DiffOp{{DiffInsert, "hello world"}},
+		},
+		{
+			name: "delete only",
+			diff: DiffWords,
+			old:  "hello world",
+			want: []DiffOp{{DiffDelete, "hello world"}},
+		},
+		{
+			name: "over the edit limit",
+			diff: DiffLines,
+			old:  "same\n" + bigOld.String() + "end\n",
+			new:  "same\n" + bigNew.String() + "end\n",
+			want: []DiffOp{
+				{DiffEqual, "same\n"},
+				{DiffDelete, bigOld.String()},
+				{DiffInsert, bigNew.String()},
+				{DiffEqual, "end\n"},
+			},
+		},
+		{
+			name: "over the size limit",
+			diff: DiffWords,
+			old:  "same " + strings.Repeat("a", maxDiffBytes),
+			new:  "same " + strings.Repeat("b", maxDiffBytes),
+			want: []DiffOp{
+				{DiffDelete, "same " + strings.Repeat("a", maxDiffBytes)},
+				{DiffInsert, "same " + strings.Repeat("b", maxDiffBytes)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := tt.diff(tt.old, tt.new)
+			if ops == nil {
+				t.Fatalf("got nil ops, want an empty slice")
+			}
+			if old, new := applyDiff(ops); old != tt.old || new != tt.new {
+				t.Errorf("ops rebuild %q -> %q, want %q -> %q", old, new, tt.old, tt.new)
+			}
+			for i := 1; i < len(ops); i++ {
+				if ops[i].Kind == ops[i-1].Kind {
+					t.Errorf("ops %d and %d are both %s, they should be merged", i-1, i, ops[i].Kind)
+				}
+			}
+			if tt.want != nil && !reflect.DeepEqual(ops, tt.want) {
+				t.Errorf("got %.200q, want %.200q", ops, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"slices"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedImageFormat = errors.New("unsupported image format")
+	ErrInvalidImageDimensions = errors.New("invalid image dimensions")
+)
+
+// AvatarSizes are the widths (and heights) of the square thumbnails we keep for every avatar.
+var AvatarSizes = []int{64, 128, 256}
+
+const (
+	MinAvatarDimension = 32
+	MaxAvatarDimension = 4096
+)
+
+var avatarFormats = []string{"png", "jpeg", "gif"}
+
+// ProcessAvatar decodes an uploaded image and returns a PNG thumbnail of its centered square for every one of
+// AvatarSizes. Re-encoding the pixels drops any metadata the original file had.
+func ProcessAvatar(data []byte) (map[int][]byte, error) {
+	// NOTE: check the dimensions before decoding, a small file can declare a huge image.
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !slices.Contains(avatarFormats, format) {
+		return nil, ErrUnsupportedImageFormat
+	}
+	if config.Width < MinAvatarDimension || config.Height < MinAvatarDimension ||
+		config.Width > MaxAvatarDimension || config.Height > MaxAvatarDimension {
+		return nil, ErrInvalidImageDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImageFormat
+	}
+
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	thumbnails := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		thumbnail := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, square, draw.Src, nil)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, thumbnail); err != nil {
+			return nil, fmt.Errorf("error encoding thumbnail: %v", err)
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+
+	return thumbnails, nil
+}
+
+// identiconCells is the width of the identicon grid, the left half is mirrored to the right.
+const identiconCells = 5
+
+// GenerateIdenticon draws the PNG of a symmetric block pattern derived from seed, the same seed always gets the
+// same picture.
+func GenerateIdenticon(seed []byte, size int) ([]byte, error) {
+	hash := sha256.Sum256(seed)
+	foreground := color.NRGBA{R: hash[0], G: hash[1], B: hash[2], A: 0xff}
+	background := color.NRGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	cellSize := size / (identiconCells + 1)
+	padding := (size - cellSize*identiconCells) / 2
+	for row := range identiconCells {
+		for col := range (identiconCells + 1) / 2 {
+			// NOTE: the first 3 bytes are the color, every cell is on when its byte is even.
+			if hash[3+row*identiconCells+col]%2 != 0 {
+				continue
+			}
+			for _, x := range []int{col, identiconCells - 1 - col} {
+				cell := image.Rect(0, 0, cellSize, cellSize).Add(image.Pt(padding+x*cellSize, padding+row*cellSize))
+				draw.Draw(img, cell, image.NewUniform(foreground), image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// stripedImage is a w by h image split in three vertical stripes, red, green and blue, so that its centered
+// square is green when w is three times h.
+func stripedImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := range w {
+		c := color.NRGBA{A: 0xff}
+		switch x * 3 / w {
+		case 0:
+			c.R = 0xff
+		case 1:
+			c.G = 0xff
+		default:
+			c.B = 0xff
+		}
+		for y := range h {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngWithDeclaredSize returns a PNG whose header declares w by h pixels, while its pixel data is only 1 by 1.
+// Decoding it fails, so it only passes for a check done on the header alone.
+func pngWithDeclaredSize(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	data := encodeImage(t, "png", image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+	// NOTE: the IHDR chunk follows the 8 bytes signature, its data starts after the length and type.
+	ihdr := data[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:4], w)
+	binary.BigEndian.PutUint32(ihdr[4:8], h)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcessAvatar(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"png", encodeImage(t, "png", stripedImage(300, 100)), nil},
+		{"jpeg", encodeImage(t, "jpeg", stripedImage(300, 100)), nil},
+		{"gif", encodeImage(t, "gif", stripedImage(300, 100)), nil},
+		{"not an image", []byte("hello, world"), ErrUnsupportedImageFormat},
+		{"bmp", append([]byte("BM"), make([]byte, 64)...), ErrUnsupportedImageFormat},
+		{"too small", encodeImage(t, "png", stripedImage(MinAvatarDimension-1, MinAvatarDimension-1)), ErrInvalidImageDimensions},
+		{"declared too wide", pngWithDeclaredSize(t, MaxAvatarDimension+1, 64), ErrInvalidImageDimensions},
+		{"declared huge", pngWithDeclaredSize(t, 1<<20, 1<<20), ErrInvalidImageDimensions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnails, err := ProcessAvatar(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(thumbnails) != len(AvatarSizes) {
+				t.Fatalf("got %d thumbnails, want %d", len(thumbnails), len(AvatarSizes))
+			}
+			for _, size := range AvatarSizes {
+				img, format, err := image.Decode(bytes.NewReader(thumbnails[size]))
+				if err != nil {
+					t.Fatalf("size %d: %v", size, err)
+				}
+				if format != "png" {
+					t.Errorf("size %d: got format %s, want png", size, format)
+				}
+				if bounds := img.Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+					t.Errorf("size %d: got %dx%d", size, bounds.Dx(), bounds.Dy())
+				}
+				// NOTE: only the green middle stripe is inside the centered square.
+				for _, pt := range []image.Point{{size / 8, size / 2}, {size / 2, size / 2}, {size - 1 - size/8, size / 2}} {
+					r, g, b, _ := img.At(pt.X, pt.Y).RGBA()
+					if g>>8 < 0xc0 || r>>8 > 0x40 || b>>8 > 0x40 {
+						t.Errorf("size %d: pixel at %v is %d,%d,%d, want green", size, pt, r>>8, g>>8, b>>8)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateIdenticon(t *testing.T) {
+	tests := []struct {
+		seed string
+		size int
+	}{
+		{"user-1", 64},
+		{"user-1", 256},
+		{"user-2", 128},
+	}
+
+	for _, tt := range tests {
+		first, err := GenerateIdenticon([]byte(tt.seed), tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := GenerateIdenticon([]byte(tt.seed), tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("seed %q: identicons differ between calls", tt.seed)
+		}
+
+		img, err := png.Decode(bytes.NewReader(first))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != tt.size || bounds.Dy() != tt.size {
+			t.Errorf("seed %q: got %dx%d, want %dx%d", tt.seed, bounds.Dx(), bounds.Dy(), tt.size, tt.size)
+		}
+		cellSize := tt.size / (identiconCells + 1)
+		padding := (tt.size - cellSize*identiconCells) / 2
+		cellCenter := func(i int) int { return padding + i*cellSize + cellSize/2 }
+		for row := range identiconCells {
+			for col := range identiconCells / 2 {
+				y := cellCenter(row)
+				if img.At(cellCenter(col), y) != img.At(cellCenter(identiconCells-1-col), y) {
+					t.Errorf("seed %q: cell %d,%d isn't mirrored", tt.seed, col, row)
+				}
+			}
+		}
+	}
+
+	one, _ := GenerateIdenticon([]byte("user-1"), 64)
+	other, _ := GenerateIdenticon([]byte("user-2"), 64)
+	if bytes.Equal(one, other) {
+		t.Errorf("different seeds got the same identicon")
+	}
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is a single key of the keyring. Keys loaded from a public key file have no private
+// part and can only verify tokens, which is how a rotated-out signing key should be kept around
+// until all the tokens it signed have expired.
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+type jwtKeyring struct {
+	signingKey *jwtKey
+	keys       map[string]*jwtKey
+}
+
+var keyring *jwtKeyring
+
+func init() {
+	kr, err := loadJwtKeyring(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_KEY_ID"))
+	if err != nil {
+		log.Fatal("error loading jwt keyring: ", err)
+	}
+	keyring = kr
+}
+
+// loadJwtKeyring loads every '<kid>.pem' file in dir as a key with the file name as its key id.
+// Ed25519 keys sign with EdDSA and RSA keys sign with RS256. The key named by signingKeyID must
+// contain a private key, it is the only one used for signing.
+func loadJwtKeyring(dir, signingKeyID string) (*jwtKeyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	kr := &jwtKeyring{keys: map[string]*jwtKey{}}
+	for _, path := range paths {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseJwtKey(strings.TrimSuffix(filepath.Base(path), ".pem"), pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		kr.keys[key.id] = key
+	}
+
+	signingKey, ok := kr.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key '%s' not found in '%s'", signingKeyID, dir)
+	}
+	if signingKey.private == nil {
+		return nil, fmt.Errorf("signing key '%s' has no private key", signingKeyID)
+	}
+	kr.signingKey = signingKey
+
+	return kr, nil
+}
+
+func parseJwtKey(id string, pemBytes []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no pem block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block type '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{id: id}
+	switch k := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, k.Public()
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// GetJWKSet returns the public part of every key in the keyring, so that other services
+// can verify our access tokens without holding any signing secret.
+func GetJWKSet() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(keyring.keys))}
+	for _, kid := range slices.Sorted(maps.Keys(keyring.keys)) {
+		key := keyring.keys[kid]
+		jwk := JWK{
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+		}
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+)
+
+// NOTE: package variables are initialized before the init functions, so this gives the keyring init
+// a throwaway signing key and the tests don't need JWT_KEYS_DIR to be set up.
+var _ = func() bool {
+	dir, err := os.MkdirTemp("", "iwonder-test-keys")
+	if err != nil {
+		panic(err)
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		panic(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "test.pem"), pemBytes, 0o600); err != nil {
+		panic(err)
+	}
+	os.Setenv("JWT_KEYS_DIR", dir)
+	os.Setenv("JWT_SIGNING_KEY_ID", "test")
+	return true
+}()
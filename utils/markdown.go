@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// MarkdownRendererVersion must be bumped whenever RenderMarkdown can produce a different output for the
+// same source, so that the cached HTML rendered by older versions gets re-rendered.
+const MarkdownRendererVersion = 1
+
+// NOTE: goldmark drops raw HTML by default, the sanitizer is what makes the output safe though,
+// links and images can still carry things like javascript: urls.
+var (
+	markdownRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Linkify),
+	)
+	markdownPolicy = newMarkdownPolicy()
+)
+
+func newMarkdownPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// NOTE: keep the language of fenced code blocks, so clients can highlight them.
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	return policy
+}
+
+// RenderMarkdown renders CommonMark source, with tables and autolinks, into sanitized HTML.
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return markdownPolicy.Sanitize(buf.String()), nil
+}
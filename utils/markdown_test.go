@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		wantContain []string
+		wantAbsent  []string
+	}{
+		{
+			name:        "javascript link",
+			source:      "[click](javascript:alert(1))",
+			wantContain: []string{"click"},
+			wantAbsent:  []string{"javascript:", "<a"},
+		},
+		{
+			name:       "javascript image",
+			source:     "![img](javascript:alert(1))",
+			wantAbsent: []string{"javascript:"},
+		},
+		{
+			name:        "raw script",
+			source:      "hello\n\n<script>alert(1)</script>\n\nworld",
+			wantContain: []string{"<p>hello</p>", "<p>world</p>"},
+			wantAbsent:  []string{"<script", "alert(1)"},
+		},
+		{
+			name:        "inline script",
+			source:      "hello <script>alert(1)</script> world",
+			wantContain: []string{"hello", "world"},
+			wantAbsent:  []string{"<script"},
+		},
+		{
+			name:       "event handler attribute",
+			source:     `<img src="x.png" onerror="alert(1)"> <a href="https://example.com" onclick="alert(1)">x</a>`,
+			wantAbsent: []string{"onerror", "onclick", "alert(1)"},
+		},
+		{
+			name:        "safe link",
+			source:      "[docs](https://example.com/docs)",
+			wantContain: []string{`href="https://example.com/docs"`, `target="_blank"`},
+		},
+		{
+			name:        "code block language",
+			source:      "```go\nfmt.Println(1)\n```",
+			wantContain: []string{`<code class="language-go">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := RenderMarkdown(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantContain {
+				if !strings.Contains(html, want) {
+					t.Errorf("html %q doesn't contain %q", html, want)
+				}
+			}
+			for _, absent := range tt.wantAbsent {
+				if strings.Contains(html, absent) {
+					t.Errorf("html %q contains %q", html, absent)
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,143 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHasher is one version of password hashing. Hashes carry the version they were made with,
+// so older versions keep verifying after a newer one becomes the current.
+type passwordHasher interface {
+	hash(password string) (string, error)
+	verify(password, hashed string) bool
+	// isCurrent reports whether the hash was made with this hasher's current parameters.
+	isCurrent(hashed string) bool
+}
+
+type argon2idHasher struct {
+	memoryKiB   uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  int
+	keyLength   uint32
+}
+
+type bcryptHasher struct{}
+
+// currentPasswordHasher hashes every new password, the configuration comes from the
+// ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM env vars.
+var currentPasswordHasher = &argon2idHasher{
+	memoryKiB:   uint32(envUint("ARGON2_MEMORY_KIB", 64*1024)),
+	iterations:  uint32(envUint("ARGON2_ITERATIONS", 3)),
+	parallelism: uint8(envUint("ARGON2_PARALLELISM", 2)),
+	saltLength:  16,
+	keyLength:   32,
+}
 
 func HashPassword(password string) (string, error) {
+	return currentPasswordHasher.hash(password)
+}
+
+func VerifyPassword(password, hashed string) bool {
+	hasher := passwordHasherFor(hashed)
+	return hasher != nil && hasher.verify(password, hashed)
+}
+
+// PasswordNeedsRehash reports whether the hash should be replaced by one from the current hasher.
+// It's meant to be checked after a successful verification, while the password is at hand.
+func PasswordNeedsRehash(hashed string) bool {
+	return !currentPasswordHasher.isCurrent(hashed)
+}
+
+func passwordHasherFor(hashed string) passwordHasher {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		return currentPasswordHasher
+	case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+		return bcryptHasher{}
+	}
+	return nil
+}
+
+// hash returns the hash in the PHC string format: $argon2id$v=19$m=<kib>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (h *argon2idHasher) hash(password string) (string, error) {
+	salt := make([]byte, h.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memoryKiB, h.parallelism, h.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memoryKiB, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) verify(password, hashed string) bool {
+	params, salt, key, err := parseArgon2idHash(hashed)
+	if err != nil {
+		return false
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.iterations, params.memoryKiB, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func (h *argon2idHasher) isCurrent(hashed string) bool {
+	params, salt, key, err := parseArgon2idHash(hashed)
+	return err == nil &&
+		params.memoryKiB == h.memoryKiB &&
+		params.iterations == h.iterations &&
+		params.parallelism == h.parallelism &&
+		len(salt) == h.saltLength &&
+		uint32(len(key)) == h.keyLength
+}
+
+func parseArgon2idHash(hashed string) (params argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memoryKiB, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+func (bcryptHasher) hash(password string) (string, error) {
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(hashedBytes), err
 }
 
-func VerifyPassword(password, hashed string) bool {
+func (bcryptHasher) verify(password, hashed string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
 	return err == nil
 }
+
+func (bcryptHasher) isCurrent(hashed string) bool {
+	return false
+}
+
+func envUint(key string, fallback uint64) uint64 {
+	if value, err := strconv.ParseUint(os.Getenv(key), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2idHash, err := HashPassword("new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: the same password hashed with weaker parameters than the current ones.
+	weakArgon2idHash, err := (&argon2idHasher{memoryKiB: 1024, iterations: 1, parallelism: 1, saltLength: 16, keyLength: 32}).hash("new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		password       string
+		hashed         string
+		wantValid      bool
+		wantNeedRehash bool
+	}{
+		{"bcrypt", "old-password", string(bcryptHash), true, true},
+		{"bcrypt wrong password", "new-password", string(bcryptHash), false, true},
+		{"argon2id", "new-password", argon2idHash, true, false},
+		{"argon2id wrong password", "old-password", argon2idHash, false, false},
+		{"argon2id old parameters", "new-password", weakArgon2idHash, true, true},
+		{"empty hash", "new-password", "", false, true},
+		{"unknown hash", "new-password", "$md5$abc", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPassword(tt.password, tt.hashed); got != tt.wantValid {
+				t.Errorf("VerifyPassword = %v, want %v", got, tt.wantValid)
+			}
+			if got := PasswordNeedsRehash(tt.hashed); got != tt.wantNeedRehash {
+				t.Errorf("PasswordNeedsRehash = %v, want %v", got, tt.wantNeedRehash)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -16,29 +16,108 @@ func GenerateRefreshToken() string {
 	return hex.EncodeToString(buf)
 }
 
+// PersonalAccessTokenPrefix tells personal access tokens apart from jwt access tokens.
+const PersonalAccessTokenPrefix = "iwp_"
+
+func GeneratePersonalAccessToken() string {
+	return PersonalAccessTokenPrefix + GenerateRefreshToken()
+}
+
+// HashToken returns the digest under which a refresh or personal access token is stored,
+// so the raw token only ever exists in the client's hands.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Every jwt we sign carries the audience it's meant for, and the keys verifying them are published in the JWKS.
+// Services that accept our access tokens must require AccessTokenAudience, otherwise they would also accept an
+// action token, like a 2fa challenge that is handed out after the password alone.
+const (
+	AccessTokenAudience = "iwonder-api"
+	ActionTokenAudience = "iwonder-action"
+	// accessTokenType is the typ header of access tokens (RFC 9068).
+	accessTokenType = "at+jwt"
+)
+
 type JwtClaims struct {
-	UserID uuid.UUID `json:"userID"`
+	UserID    uuid.UUID `json:"userID"`
+	SessionID uuid.UUID `json:"sessionID"`
+	Role      string    `json:"role"`
 	jwt.RegisteredClaims
 }
 
 func GenerateJWTAccessToken(claims JwtClaims) (string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return jwtToken.SignedString([]byte(os.Getenv("SECRET")))
+	claims.Audience = jwt.ClaimStrings{AccessTokenAudience}
+	return signJWT(claims, accessTokenType)
 }
 
 func ParseJWTTokenString(tokenString string) (*JwtClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JwtClaims{}, func(token *jwt.Token) (any, error) {
-		if token.Method.Alg() != jwt.SigningMethodHS256.Name {
+	var claims JwtClaims
+	if err := parseJWT(tokenString, &claims, AccessTokenAudience, accessTokenType); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+const (
+	ActionVerifyEmail    = "verify_email"
+	ActionResetPassword  = "reset_password"
+	ActionTwoFactorLogin = "two_factor_login"
+	// ActionDownloadDataExport tokens sign the download links of data exports, they aren't single-use.
+	ActionDownloadDataExport = "download_data_export"
+)
+
+// ActionClaims are carried by the single-use tokens we send by email or hand out to finish a login.
+// The signature proves we issued the token, while its ID is what the database tracks to allow using it only once.
+type ActionClaims struct {
+	UserID  uuid.UUID `json:"userID"`
+	Purpose string    `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func GenerateJWTActionToken(claims ActionClaims) (string, error) {
+	claims.Audience = jwt.ClaimStrings{ActionTokenAudience}
+	return signJWT(claims, "JWT")
+}
+
+func ParseJWTActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	var claims ActionClaims
+	if err := parseJWT(tokenString, &claims, ActionTokenAudience, "JWT"); err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return &claims, nil
+}
+
+func signJWT(claims jwt.Claims, typ string) (string, error) {
+	jwtToken := jwt.NewWithClaims(keyring.signingKey.method, claims)
+	jwtToken.Header["kid"] = keyring.signingKey.id
+	jwtToken.Header["typ"] = typ
+	return jwtToken.SignedString(keyring.signingKey.private)
+}
+
+// parseJWT verifies the token and requires it to be of type typ and for audience, so that one kind of token
+// can't be used in place of another.
+func parseJWT(tokenString string, claims jwt.Claims, audience, typ string) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		if tokenType, _ := token.Header["typ"].(string); tokenType != typ {
+			return nil, fmt.Errorf("unexpected token type")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keyring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id")
+		}
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method")
 		}
-		return []byte(os.Getenv("SECRET")), nil
-	})
+		return key.public, nil
+	}, jwt.WithAudience(audience))
 	if err != nil {
-		return nil, jwt.ErrTokenSignatureInvalid
-	}
-	claims, ok := token.Claims.(*JwtClaims)
-	if !ok {
-		return nil, jwt.ErrTokenInvalidClaims
+		return jwt.ErrTokenSignatureInvalid
 	}
-	return claims, nil
+	return nil
 }
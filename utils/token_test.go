@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestJWTTokenTypes(t *testing.T) {
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	accessToken, err := GenerateJWTAccessToken(JwtClaims{
+		UserID:           uuid.New(),
+		SessionID:        uuid.New(),
+		Role:             "user",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeToken, err := GenerateJWTActionToken(ActionClaims{
+		UserID:           uuid.New(),
+		Purpose:          ActionTwoFactorLogin,
+		RegisteredClaims: jwt.RegisteredClaims{ID: uuid.NewString(), ExpiresAt: expiresAt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: a token signed with our key but without an audience, like the action tokens used to be.
+	var legacyToken string
+	{
+		jwtToken := jwt.NewWithClaims(keyring.signingKey.method, JwtClaims{
+			UserID:           uuid.New(),
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt},
+		})
+		jwtToken.Header["kid"] = keyring.signingKey.id
+		if legacyToken, err = jwtToken.SignedString(keyring.signingKey.private); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantAccess bool
+		wantAction bool
+	}{
+		{"access token", accessToken, true, false},
+		{"action token", challengeToken, false, true},
+		{"token without audience", legacyToken, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJWTTokenString(tt.token); (err == nil) != tt.wantAccess {
+				t.Errorf("accepted as an access token: %v, want %v", err == nil, tt.wantAccess)
+			}
+			if _, err := ParseJWTActionToken(tt.token, ActionTwoFactorLogin); (err == nil) != tt.wantAction {
+				t.Errorf("accepted as an action token: %v, want %v", err == nil, tt.wantAction)
+			}
+		})
+	}
+
+	if _, err := ParseJWTActionToken(challengeToken, ActionResetPassword); err == nil {
+		t.Errorf("action token accepted for another purpose")
+	}
+}
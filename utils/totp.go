@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238), these are the defaults every authenticator app supports.
+const (
+	totpPeriodSeconds = 30
+	totpDigits        = 6
+	// totpSkewSteps is how many steps before and after the current one are accepted, to tolerate clock drift.
+	totpSkewSteps = 1
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() string {
+	buf := make([]byte, 20)
+	rand.Read(buf)
+	return base32NoPadding.EncodeToString(buf)
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI that authenticator apps read, usually through a QR code.
+func TOTPProvisioningURI(secret, issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriodSeconds))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+// VerifyTOTP checks the code against the steps around t. To prevent replaying a code, only steps
+// after lastUsedStep are accepted, the matched step is returned to be stored as the new lastUsedStep.
+func VerifyTOTP(secret, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	currentStep := t.Unix() / totpPeriodSeconds
+	for step := currentStep - totpSkewSteps; step <= currentStep+totpSkewSteps; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpCode(key []byte, step int64) string {
+	mac := hmac.New(sha1.New, key)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%uint32(math.Pow10(totpDigits)))
+}
+
+// GenerateRecoveryCodes returns n one-time codes formatted like 'xxxxx-xxxxx'.
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, 0, n)
+	for range n {
+		buf := make([]byte, 7)
+		rand.Read(buf)
+		code := strings.ToLower(base32NoPadding.EncodeToString(buf))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes
+}
+
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
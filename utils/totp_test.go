@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA1 key of the RFC 6238 test vectors.
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// NOTE: the RFC vectors have 8 digits, a 6 digits code is their last 6.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Key, tt.unix/totpPeriodSeconds); got != tt.want {
+			t.Errorf("totpCode at %d = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := base32NoPadding.EncodeToString(rfc6238Key)
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriodSeconds
+
+	tests := []struct {
+		name         string
+		secret       string
+		code         string
+		lastUsedStep int64
+		wantStep     int64
+		wantOk       bool
+	}{
+		{"current step", secret, "050471", 0, step, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050471", 0, step, true},
+		{"previous step", secret, totpCode(rfc6238Key, step-1), 0, step - 1, true},
+		{"next step", secret, totpCode(rfc6238Key, step+1), 0, step + 1, true},
+		{"outside the skew", secret, totpCode(rfc6238Key, step-2), 0, 0, false},
+		{"replayed step", secret, "050471", step, 0, false},
+		{"wrong code", secret, "000000", 0, 0, false},
+		{"wrong length", secret, "50471", 0, 0, false},
+		{"invalid secret", "not base32!", "050471", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOk := VerifyTOTP(tt.secret, tt.code, now, tt.lastUsedStep)
+			if gotStep != tt.wantStep || gotOk != tt.wantOk {
+				t.Errorf("VerifyTOTP = %d, %v, want %d, %v", gotStep, gotOk, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}